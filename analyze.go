@@ -0,0 +1,148 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// WordlistStats reports the lookups needed to check a wordlist, see
+// AnalyzeWordlist.
+type WordlistStats struct {
+	Inputs  int // inputs analyzed, e.g., non-empty lines read
+	Invalid int // inputs that cannot be checked, e.g., malformed hashes
+	Local   int // inputs decided without a request, e.g., by WithBlocklist
+
+	// Hashes and Prefixes are the distinct hashes and range prefixes of
+	// the valid inputs. A cache set with WithCache of Prefixes entries
+	// holds every range of the wordlist.
+	Hashes   int
+	Prefixes int
+
+	// Requests is the estimated number of requests to check the wordlist
+	// with CheckPwnedPasswords or a cache holding every range, i.e., the
+	// distinct prefixes of the inputs that are not decided locally.
+	// Checking each input without a cache needs Lookups requests instead.
+	// Neither includes retries or mirror failover.
+	Requests int
+	Lookups  int
+}
+
+// AnalyzeWordlist reads inputs from r, one per line, and reports how many
+// unique prefixes and requests checking them with lookup and mode would
+// need, e.g., to size the cache or estimate the cost of a scan ahead of
+// time. Inputs are hashed with the client's normalization, prefix length,
+// blocklist, allowlist, and Bloom filter as they would be checked, but no
+// requests are made. Lines are used as given, without their line ending,
+// and empty lines are skipped. Lines longer than bufio.MaxScanTokenSize are
+// an error; use AnalyzeInputs for inputs that are already parsed.
+func (c *PwnedClient) AnalyzeWordlist(r io.Reader, lookup, mode string) (WordlistStats, error) {
+	a, err := c.newWordlistAnalyzer(lookup, mode)
+	if err != nil {
+		return WordlistStats{}, err
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if text := scanner.Text(); text != "" {
+			a.add(text)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return WordlistStats{}, err
+	}
+	return a.stats(), nil
+}
+
+// AnalyzeInputs is like AnalyzeWordlist but analyzes each of inputs as
+// given, including empty inputs, which are checked like any other.
+func (c *PwnedClient) AnalyzeInputs(inputs []string, lookup, mode string) (WordlistStats, error) {
+	a, err := c.newWordlistAnalyzer(lookup, mode)
+	if err != nil {
+		return WordlistStats{}, err
+	}
+	for _, text := range inputs {
+		a.add(text)
+	}
+	return a.stats(), nil
+}
+
+// wordlistAnalyzer tallies the inputs added to it for AnalyzeWordlist and
+// AnalyzeInputs.
+type wordlistAnalyzer struct {
+	c            *PwnedClient
+	lookup, mode string
+	WordlistStats
+	hashes    map[string]bool
+	prefixes  map[string]bool
+	requested map[string]bool // prefixes needing a request
+}
+
+// newWordlistAnalyzer returns an analyzer for lookup and mode, or an error
+// if either is invalid.
+func (c *PwnedClient) newWordlistAnalyzer(lookup, mode string) (*wordlistAnalyzer, error) {
+	if _, ok := hashLengths[mode]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+	if lookup != "hash" && lookup != "password" {
+		return nil, fmt.Errorf("invalid lookup type: %s", lookup)
+	}
+	return &wordlistAnalyzer{
+		c:         c,
+		lookup:    lookup,
+		mode:      mode,
+		hashes:    make(map[string]bool),
+		prefixes:  make(map[string]bool),
+		requested: make(map[string]bool),
+	}, nil
+}
+
+// add tallies the input text.
+func (a *wordlistAnalyzer) add(text string) {
+	a.Inputs++
+
+	var hash string
+	var err error
+	if a.lookup == "hash" {
+		hash, err = CanonicalizeHash(text, a.mode)
+	} else {
+		hash, err = a.c.passwordHash(text, a.mode)
+	}
+	if err != nil {
+		a.Invalid++
+		return
+	}
+
+	prefix := hash[:a.c.prefixLen()]
+	a.hashes[hash] = true
+	a.prefixes[prefix] = true
+	if _, ok := a.c.localResult(hash, a.mode); ok {
+		a.Local++
+		return
+	}
+	a.Lookups++
+	a.requested[prefix] = true
+}
+
+// stats returns the tally of the inputs added so far.
+func (a *wordlistAnalyzer) stats() WordlistStats {
+	stats := a.WordlistStats
+	stats.Hashes = len(a.hashes)
+	stats.Prefixes = len(a.prefixes)
+	stats.Requests = len(a.requested)
+	return stats
+}
+
+// AnalyzeWordlist is like PwnedClient.AnalyzeWordlist but uses the default
+// client, see SetDefaultClient.
+func AnalyzeWordlist(r io.Reader, lookup, mode string) (WordlistStats, error) {
+	return defaultClient().AnalyzeWordlist(r, lookup, mode)
+}
+
+// AnalyzeInputs is like PwnedClient.AnalyzeInputs but uses the default
+// client, see SetDefaultClient.
+func AnalyzeInputs(inputs []string, lookup, mode string) (WordlistStats, error) {
+	return defaultClient().AnalyzeInputs(inputs, lookup, mode)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestAnalyzeWordlist(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+	}))
+	defer server.Close()
+
+	blocklist := exposed.NewHashList()
+	blocklist.AddPassword("blocked")
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithBlocklist(blocklist))
+
+	tests := []struct {
+		name   string
+		input  string
+		lookup string
+		mode   string
+		want   exposed.WordlistStats
+	}{
+		{
+			name:   "passwords",
+			input:  "password\npassword\n\nhello\nblocked\n",
+			lookup: "password",
+			mode:   "sha1",
+			want:   exposed.WordlistStats{Inputs: 4, Local: 1, Hashes: 3, Prefixes: 3, Requests: 2, Lookups: 3},
+		},
+		{
+			name:   "hashes",
+			input:  "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n5baa6" + strings.Repeat("f", 35) + "\r\nnot a hash\n",
+			lookup: "hash",
+			mode:   "sha1",
+			want:   exposed.WordlistStats{Inputs: 3, Invalid: 1, Hashes: 2, Prefixes: 1, Requests: 1, Lookups: 2},
+		},
+		{
+			name:   "NTLM",
+			input:  "password\n",
+			lookup: "password",
+			mode:   "ntlm",
+			want:   exposed.WordlistStats{Inputs: 1, Hashes: 1, Prefixes: 1, Requests: 1, Lookups: 1},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := c.AnalyzeWordlist(strings.NewReader(tc.input), tc.lookup, tc.mode)
+			if err != nil {
+				t.Fatalf("AnalyzeWordlist() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("AnalyzeWordlist() = %+v, expected %+v", got, tc.want)
+			}
+		})
+	}
+
+	if requests != 0 {
+		t.Errorf("got %d requests, expected none", requests)
+	}
+
+	if _, err := c.AnalyzeWordlist(strings.NewReader("password\n"), "password", "md5"); !errors.Is(err, exposed.ErrInvalidMode) {
+		t.Errorf("AnalyzeWordlist() error = %v, expected %v", err, exposed.ErrInvalidMode)
+	}
+	if _, err := c.AnalyzeWordlist(strings.NewReader("password\n"), "text", "sha1"); err == nil {
+		t.Error("AnalyzeWordlist() expected error for invalid lookup")
+	}
+}
+
+func TestAnalyzeInputs(t *testing.T) {
+	c := exposed.NewPwnedClient(nil, "http://127.0.0.1:0")
+
+	// empty inputs are analyzed, unlike the empty lines of a wordlist
+	inputs := []string{"password", "", "password", strings.Repeat("x", 100*1024)}
+	got, err := c.AnalyzeInputs(inputs, "password", "sha1")
+	if err != nil {
+		t.Fatalf("AnalyzeInputs() error = %v", err)
+	}
+	want := exposed.WordlistStats{Inputs: 4, Hashes: 3, Prefixes: 3, Requests: 3, Lookups: 4}
+	if got != want {
+		t.Errorf("AnalyzeInputs() = %+v, expected %+v", got, want)
+	}
+
+	if _, err := c.AnalyzeInputs(inputs, "password", "md5"); !errors.Is(err, exposed.ErrInvalidMode) {
+		t.Errorf("AnalyzeInputs() error = %v, expected %v", err, exposed.ErrInvalidMode)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// APIKeyEnv is the environment variable holding the API key used when
+// neither WithAPIKey nor WithAPIKeyFile is given.
+const APIKeyEnv = "HIBP_API_KEY"
+
+// APIKeyFileEnv is the environment variable holding the path of a file
+// containing the API key, e.g., a Docker secret, used when no other source
+// of the key is given.
+const APIKeyFileEnv = "HIBP_API_KEY_FILE"
+
+// apiKeyHeader is the request header carrying the API key.
+const apiKeyHeader = "hibp-api-key"
+
+// ErrAPIKey is returned by requests if the API key could not be read.
+var ErrAPIKey = errors.New("invalid API key")
+
+// WithAPIKey sets the API key sent with each request in the hibp-api-key
+// header. It takes precedence over WithAPIKeyFile and the environment.
+func WithAPIKey(key string) Option {
+	return func(c *PwnedClient) {
+		c.apiKey = key
+	}
+}
+
+// WithAPIKeyFile reads the API key from the file at path, ignoring
+// surrounding whitespace. It takes precedence over the environment. If the
+// file cannot be read or is empty, every request fails with ErrAPIKey.
+func WithAPIKeyFile(path string) Option {
+	return func(c *PwnedClient) {
+		c.apiKeyFile = path
+	}
+}
+
+// resolveAPIKey sets the API key of the client from, in order of
+// precedence, WithAPIKey, WithAPIKeyFile, APIKeyEnv, and APIKeyFileEnv. The
+// key itself is never included in errors.
+func (c *PwnedClient) resolveAPIKey() {
+	if c.apiKey != "" {
+		return
+	}
+
+	path := c.apiKeyFile
+	if path == "" {
+		if key := os.Getenv(APIKeyEnv); key != "" {
+			c.apiKey = strings.TrimSpace(key)
+			return
+		}
+		path = os.Getenv(APIKeyFileEnv)
+	}
+	if path == "" {
+		return
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		c.setConfigErr(fmt.Errorf("%w: %w", ErrAPIKey, err))
+		return
+	}
+	c.apiKey = strings.TrimSpace(string(b))
+	if c.apiKey == "" {
+		c.setConfigErr(fmt.Errorf("%w: %s is empty", ErrAPIKey, path))
+	}
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestAPIKey(t *testing.T) {
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	if err := os.WriteFile(keyFile, []byte("file-key\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	envKeyFile := filepath.Join(dir, "env-key")
+	if err := os.WriteFile(envKeyFile, []byte("env-file-key"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		opts    []exposed.Option
+		wantKey string
+	}{
+		{name: "none"},
+		{
+			name:    "env",
+			env:     map[string]string{exposed.APIKeyEnv: "env-key"},
+			wantKey: "env-key",
+		},
+		{
+			name:    "env file",
+			env:     map[string]string{exposed.APIKeyFileEnv: envKeyFile},
+			wantKey: "env-file-key",
+		},
+		{
+			name:    "file over env",
+			env:     map[string]string{exposed.APIKeyEnv: "env-key"},
+			opts:    []exposed.Option{exposed.WithAPIKeyFile(keyFile)},
+			wantKey: "file-key",
+		},
+		{
+			name:    "key over file",
+			env:     map[string]string{exposed.APIKeyEnv: "env-key"},
+			opts:    []exposed.Option{exposed.WithAPIKey("explicit"), exposed.WithAPIKeyFile(keyFile)},
+			wantKey: "explicit",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(exposed.APIKeyEnv, "")
+			t.Setenv(exposed.APIKeyFileEnv, "")
+			for k, v := range tc.env {
+				t.Setenv(k, v)
+			}
+
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("hibp-api-key")
+				_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL, tc.opts...)
+			if _, err := c.CheckPwnedPassword("password", "sha1"); err != nil {
+				t.Fatalf("CheckPwnedPassword() error = %v", err)
+			}
+			if got != tc.wantKey {
+				t.Errorf("hibp-api-key = %q, expected %q", got, tc.wantKey)
+			}
+		})
+	}
+}
+
+func TestAPIKeyFileError(t *testing.T) {
+	t.Setenv(exposed.APIKeyEnv, "")
+	t.Setenv(exposed.APIKeyFileEnv, "")
+
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty")
+	if err := os.WriteFile(empty, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing"), empty} {
+		c := exposed.NewPwnedClient(&http.Client{}, "http://127.0.0.1:0", exposed.WithAPIKeyFile(path))
+		_, err := c.CheckPwnedPassword("password", "sha1")
+		if !errors.Is(err, exposed.ErrAPIKey) {
+			t.Errorf("CheckPwnedPassword() error = %v, expected %v", err, exposed.ErrAPIKey)
+		}
+	}
+
+	c := exposed.NewPwnedClient(&http.Client{}, "http://127.0.0.1:0", exposed.WithAPIKey("s3cr3t"))
+	if _, err := c.CheckPwnedPassword("password", "sha1"); err != nil && strings.Contains(err.Error(), "s3cr3t") {
+		t.Errorf("error %q contains the API key", err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes increasing delays for retry loops. The zero value
+// returns zero delays. A Backoff is not safe for concurrent use, so each
+// retry loop should use its own copy.
+type Backoff struct {
+	// Base is the delay returned by the first call to Next.
+	Base time.Duration
+
+	// Max limits the delay. Zero means no limit.
+	Max time.Duration
+
+	// Multiplier is the factor applied to the delay after each call to
+	// Next. If not positive, 2 is used.
+	Multiplier float64
+
+	// Jitter is the fraction, from 0 to 1, of each delay that is
+	// randomized. A delay d is reduced by a random amount up to Jitter*d,
+	// so jitter never makes a delay exceed Max.
+	Jitter float64
+
+	attempt int
+}
+
+// Next returns the delay before the next attempt and advances the backoff.
+func (b *Backoff) Next() time.Duration {
+	attempt := b.attempt
+	b.attempt++
+	if b.Base <= 0 {
+		return 0
+	}
+
+	mult := b.Multiplier
+	if mult <= 0 {
+		mult = 2
+	}
+
+	d := float64(b.Base) * math.Pow(mult, float64(attempt))
+
+	if b.Max > 0 && d > float64(b.Max) {
+		d = float64(b.Max)
+	}
+
+	if b.Jitter > 0 {
+		j := math.Min(b.Jitter, 1)
+		d -= d * j * rand.Float64()
+	}
+
+	// float64(math.MaxInt64) rounds up to 2^63, which overflows a Duration
+	if d >= float64(math.MaxInt64) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(d)
+}
+
+// Reset restarts the backoff so that Next returns Base again.
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
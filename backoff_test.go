@@ -0,0 +1,89 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		name string
+		b    exposed.Backoff
+		want []time.Duration
+	}{
+		{
+			name: "zero",
+			want: []time.Duration{0, 0, 0},
+		},
+		{
+			name: "default multiplier",
+			b:    exposed.Backoff{Base: time.Second},
+			want: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second},
+		},
+		{
+			name: "max",
+			b:    exposed.Backoff{Base: time.Second, Max: 3 * time.Second, Multiplier: 3},
+			want: []time.Duration{time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			name: "constant",
+			b:    exposed.Backoff{Base: time.Second, Multiplier: 1},
+			want: []time.Duration{time.Second, time.Second, time.Second},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			for i, want := range tc.want {
+				if got := tc.b.Next(); got != want {
+					t.Errorf("Next() #%d = %v, expected %v", i, got, want)
+				}
+			}
+
+			tc.b.Reset()
+			if got := tc.b.Next(); got != tc.want[0] {
+				t.Errorf("Next() after Reset() = %v, expected %v", got, tc.want[0])
+			}
+		})
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := exposed.Backoff{Base: time.Second, Max: 8 * time.Second, Jitter: 0.5}
+	for i := 0; i < 100; i++ {
+		full := time.Second << min(i, 3)
+		d := b.Next()
+		if d < full/2 || d > full {
+			t.Fatalf("Next() #%d = %v, expected in [%v, %v]", i, d, full/2, full)
+		}
+	}
+}
+
+func TestBackoffLargeAttempt(t *testing.T) {
+	// without Max, the delay grows past the largest Duration, and then
+	// to +Inf, but never wraps around to a negative delay
+	b := exposed.Backoff{Base: time.Second}
+	var prev time.Duration
+	for i := 0; i < 2000; i++ {
+		d := b.Next()
+		if d < prev {
+			t.Fatalf("Next() #%d = %v, expected at least %v", i, d, prev)
+		}
+		prev = d
+	}
+	if prev != math.MaxInt64 {
+		t.Errorf("Next() = %v, expected %v", prev, time.Duration(math.MaxInt64))
+	}
+}
+
+func TestBackoffAllocs(t *testing.T) {
+	b := exposed.Backoff{Base: time.Millisecond, Max: time.Second, Jitter: 0.2}
+	if allocs := testing.AllocsPerRun(100, func() { b.Next() }); allocs != 0 {
+		t.Errorf("Next() allocates %v times, expected 0", allocs)
+	}
+}
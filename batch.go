@@ -0,0 +1,306 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultConcurrency is the number of ranges fetched at once by
+// CheckPwnedPasswords if not set with WithConcurrency.
+const DefaultConcurrency = 8
+
+// Status is the outcome of checking one password of a batch. The zero value
+// is not a valid status.
+type Status int
+
+const (
+	StatusFound    Status = iota + 1 // checked and exposed in breaches
+	StatusNotFound                   // checked and not exposed in breaches
+	StatusErrored                    // not checked, see BatchResult.Err
+)
+
+// String returns the name of the status, e.g., "found".
+func (s Status) String() string {
+	switch s {
+	case StatusFound:
+		return "found"
+	case StatusNotFound:
+		return "not found"
+	case StatusErrored:
+		return "errored"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// BatchResult is the result of checking one password of a batch. Status
+// tells a password that was not found, with a zero Count, apart from one
+// that could not be checked.
+type BatchResult struct {
+	Result
+	Status Status
+	Err    error // error checking the password, nil if Result is valid
+}
+
+// newBatchResult returns the batch result for result and err.
+func newBatchResult(result Result, err error) BatchResult {
+	switch {
+	case err != nil:
+		return BatchResult{Status: StatusErrored, Err: err}
+	case result.Found():
+		return BatchResult{Result: result, Status: StatusFound}
+	default:
+		return BatchResult{Result: result, Status: StatusNotFound}
+	}
+}
+
+// WithConcurrency sets the number of ranges fetched at once by
+// CheckPwnedPasswords. If n is not positive, DefaultConcurrency is used.
+func WithConcurrency(n int) Option {
+	return func(c *PwnedClient) {
+		c.concurrency = n
+	}
+}
+
+// WithRampUp staggers the start of the workers used by CheckPwnedPasswords
+// over d, with random jitter, instead of starting them all at once, which
+// smooths the request rate at the start of a large batch and avoids
+// tripping the API's rate limiter. Ramp-up is disabled if d is not
+// positive, which is the default.
+func WithRampUp(d time.Duration) Option {
+	return func(c *PwnedClient) {
+		c.rampUp = d
+	}
+}
+
+// WithFailFast stops CheckPwnedPasswords and CheckPwnedPasswordsFunc at the
+// first range that cannot be fetched, e.g., if the API is unreachable or
+// rejects the API key, instead of continuing with the other ranges, which is
+// the default. The fetches in flight are canceled, the ranges not yet
+// fetched are skipped, and their passwords are reported as errored with the
+// error that stopped the batch, which is also returned.
+func WithFailFast(enabled bool) Option {
+	return func(c *PwnedClient) {
+		c.failFast = enabled
+	}
+}
+
+// workers returns the number of workers to use for n ranges.
+func (c *PwnedClient) workers(n int) int {
+	w := c.concurrency
+	if w <= 0 {
+		w = DefaultConcurrency
+	}
+	if n < w {
+		w = n
+	}
+	return w
+}
+
+// startDelay returns how long worker i of n waits before its first request.
+// The ramp-up is divided into n equal steps and each worker starts at a
+// random time within its step.
+func (c *PwnedClient) startDelay(i, n int) time.Duration {
+	if c.rampUp <= 0 {
+		return 0
+	}
+	step := c.rampUp / time.Duration(n)
+	if step <= 0 {
+		return 0
+	}
+	return time.Duration(i)*step + time.Duration(rand.Int63n(int64(step)))
+}
+
+// CheckPwnedPasswords checks if each of passwords has been exposed in
+// breaches using hashes of type mode. Passwords whose hashes share a prefix
+// are checked with a single request, and up to WithConcurrency ranges are
+// fetched at once. The results are in the same order as passwords. Errors
+// for individual passwords, including a done ctx, are reported in their
+// BatchResult. An error is returned with no results if mode is invalid, or
+// with the results if a range could not be fetched and WithFailFast is set.
+//
+// If ctx has a deadline, it is shared fairly so that slow early requests
+// cannot starve later ones: each range request is limited to the time left
+// divided by the number of rounds of requests still needed, i.e., the
+// pending ranges divided by the number of workers, rounded up. Time not used
+// by fast requests is left for the rest, and the last round may use all of
+// the time left. A range that exceeds its share fails with
+// context.DeadlineExceeded.
+func (c *PwnedClient) CheckPwnedPasswords(ctx context.Context, passwords []string, mode string) ([]BatchResult, error) {
+	results := make([]BatchResult, len(passwords))
+	err := c.checkPasswords(ctx, passwords, mode, func(i int, r BatchResult) {
+		results[i] = r
+	})
+	if errors.Is(err, ErrInvalidMode) {
+		return nil, err
+	}
+	return results, err
+}
+
+// CheckPwnedPasswordsFunc is like CheckPwnedPasswords but calls fn with
+// each password and its result as soon as it is known instead of
+// collecting the results, so memory use does not grow with the results of
+// a large batch. Passwords are grouped by prefix and fetched in the same
+// way, so fn is called in no particular order, but never concurrently. The
+// error is as for CheckPwnedPasswords, and fn is not called if mode is
+// invalid.
+func (c *PwnedClient) CheckPwnedPasswordsFunc(ctx context.Context, passwords []string, mode string, fn func(input string, r BatchResult)) error {
+	return c.checkPasswords(ctx, passwords, mode, func(i int, r BatchResult) {
+		fn(passwords[i], r)
+	})
+}
+
+// checkPasswords checks passwords as described by CheckPwnedPasswords and
+// calls emit with the index and result of each password once known. Calls
+// to emit are serialized. With fail-fast, the first range fetch error
+// cancels the rest and is returned.
+func (c *PwnedClient) checkPasswords(ctx context.Context, passwords []string, mode string, emit func(i int, r BatchResult)) error {
+	if _, ok := hashLengths[mode]; !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+
+	var mu sync.Mutex
+	emitLocked := func(i int, r BatchResult) {
+		mu.Lock()
+		defer mu.Unlock()
+		emit(i, r)
+	}
+
+	hashes := make([]string, len(passwords))
+	groups := make(map[string][]int) // prefix to indexes of passwords
+
+	for i, password := range passwords {
+		hash, err := c.passwordHash(password, mode)
+		if err != nil {
+			emitLocked(i, newBatchResult(Result{}, err))
+			continue
+		}
+		if result, ok := c.localResult(hash, mode); ok {
+			emitLocked(i, newBatchResult(result, nil))
+			continue
+		}
+		hashes[i] = hash
+		prefix := hash[:c.prefixLen()]
+		groups[prefix] = append(groups[prefix], i)
+	}
+
+	prefixes := make([]string, 0, len(groups))
+	for prefix := range groups {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+
+	jobs := make(chan string, len(prefixes))
+	for _, prefix := range prefixes {
+		jobs <- prefix
+	}
+	close(jobs)
+
+	n := c.workers(len(prefixes))
+	var pending atomic.Int64 // ranges not yet finished
+	pending.Store(int64(len(prefixes)))
+
+	// ctx is only canceled here by an error with fail-fast, which is the
+	// cause reported for the ranges not yet fetched
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var (
+		stopOnce sync.Once
+		stopErr  error
+	)
+	stop := func(err error) {
+		stopOnce.Do(func() {
+			stopErr = err
+			cancel(err)
+		})
+	}
+
+	// every worker drains jobs, even once ctx is done, so that each
+	// password is emitted
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		delay := c.startDelay(i, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			// a done ctx is reported by the requests, so it is not
+			// checked here
+			if delay > 0 {
+				select {
+				case <-ctx.Done():
+				case <-c.after(delay):
+				}
+			}
+
+			for prefix := range jobs {
+				rctx, rcancel := fairContext(ctx, pending.Load(), n)
+				err := c.checkGroup(rctx, prefix, mode, groups[prefix], hashes, emitLocked)
+				rcancel()
+				pending.Add(-1)
+				if err != nil && c.failFast && ctx.Err() == nil {
+					stop(fmt.Errorf("range %s: %w", prefix, err))
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	return stopErr
+}
+
+// fairContext returns ctx limited to a fair share of the time left before
+// its deadline when pending ranges, including the one about to start, are
+// fetched by workers at once. If ctx has no deadline, it is returned as is.
+func fairContext(ctx context.Context, pending int64, workers int) (context.Context, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !ok || pending <= 0 || workers <= 0 {
+		return ctx, func() {}
+	}
+	rounds := (pending + int64(workers) - 1) / int64(workers)
+	return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(rounds))
+}
+
+// checkGroup fetches the range for prefix and calls emit with the result
+// for each of the hashes at idxs, which all share the prefix. The error
+// fetching the range, if any, is also returned. If ctx is already done, the
+// range is not fetched.
+func (c *PwnedClient) checkGroup(ctx context.Context, prefix, mode string, idxs []int, hashes []string, emit func(i int, r BatchResult)) error {
+	setErr := func(err error) error {
+		for _, i := range idxs {
+			emit(i, newBatchResult(Result{}, err))
+		}
+		return err
+	}
+
+	if ctx.Err() != nil {
+		return setErr(context.Cause(ctx))
+	}
+
+	body, contentType, mirror, err := c.openRange(ctx, prefix, mode)
+	if err != nil {
+		return setErr(err)
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return setErr(err)
+	}
+
+	for _, i := range idxs {
+		result, err := processResponse(bytes.NewReader(data), contentType, hashes[i], len(prefix))
+		result.Mirror = mirror
+		emit(i, newBatchResult(result, err))
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestCheckPwnedPasswords(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/5BAA6" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithConcurrency(2))
+
+	// both "password" hashes are checked with one request, "abc" has another prefix
+	passwords := []string{"password", "abc", "password"}
+	results, err := c.CheckPwnedPasswords(context.Background(), passwords, "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedPasswords() error = %v", err)
+	}
+	if len(results) != len(passwords) {
+		t.Fatalf("got %d results, expected %d", len(results), len(passwords))
+	}
+
+	for _, i := range []int{0, 2} {
+		if results[i].Err != nil || results[i].Count != 10434004 || results[i].Status != exposed.StatusFound {
+			t.Errorf("results[%d] = %+v, expected found with count 10434004", i, results[i])
+		}
+	}
+	if results[1].Err == nil || results[1].Status != exposed.StatusErrored {
+		t.Errorf("results[1] = %+v, expected error", results[1])
+	}
+
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d requests, expected 2", got)
+	}
+
+	_, err = c.CheckPwnedPasswords(context.Background(), passwords, "md5")
+	if !errors.Is(err, exposed.ErrInvalidMode) {
+		t.Errorf("CheckPwnedPasswords() error = %v, expected %v", err, exposed.ErrInvalidMode)
+	}
+}
+
+func TestCheckPwnedPasswordsFunc(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.URL.Path != "/5BAA6" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithConcurrency(2))
+
+	// fn is never called concurrently, so the counts need no lock
+	counts := make(map[string]int)
+	failed := 0
+	passwords := []string{"password", "abc", "password"}
+	err := c.CheckPwnedPasswordsFunc(context.Background(), passwords, "sha1", func(input string, r exposed.BatchResult) {
+		if r.Status == exposed.StatusErrored {
+			failed++
+			return
+		}
+		counts[input] += r.Count
+	})
+	if err != nil {
+		t.Fatalf("CheckPwnedPasswordsFunc() error = %v", err)
+	}
+
+	if counts["password"] != 2*10434004 || failed != 1 {
+		t.Errorf("counts = %v and %d failed, expected two results for \"password\" and 1 failed", counts, failed)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d requests, expected 2", got)
+	}
+
+	err = c.CheckPwnedPasswordsFunc(context.Background(), passwords, "md5", func(string, exposed.BatchResult) {
+		t.Error("fn called for invalid mode")
+	})
+	if !errors.Is(err, exposed.ErrInvalidMode) {
+		t.Errorf("CheckPwnedPasswordsFunc() error = %v, expected %v", err, exposed.ErrInvalidMode)
+	}
+}
+
+func TestWithRampUp(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	const workers = 4
+	rampUp := 400 * time.Millisecond
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithClock(clock),
+		exposed.WithConcurrency(workers),
+		exposed.WithRampUp(rampUp))
+
+	// each password has a different prefix, so each worker gets one
+	passwords := []string{"a", "b", "c", "d"}
+	results, err := c.CheckPwnedPasswords(context.Background(), passwords, "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedPasswords() error = %v", err)
+	}
+	for i, r := range results {
+		if r.Err != nil {
+			t.Errorf("results[%d] error = %v", i, r.Err)
+		}
+	}
+
+	delays := append([]time.Duration(nil), clock.delays...)
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+
+	// the first worker may start immediately without waiting
+	if len(delays) < workers-1 {
+		t.Fatalf("delays = %v, expected at least %d", delays, workers-1)
+	}
+	step := rampUp / workers
+	for i, d := range delays[len(delays)-(workers-1):] {
+		lo := time.Duration(i+1) * step
+		if d < lo || d >= lo+step {
+			t.Errorf("delay %d = %v, expected in [%v, %v)", i+1, d, lo, lo+step)
+		}
+	}
+}
+
+func TestCheckPwnedPasswordsFairDeadline(t *testing.T) {
+	// the first range never responds, the rest respond at once
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithConcurrency(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer cancel()
+
+	// three passwords with different prefixes
+	results, err := c.CheckPwnedPasswords(ctx, []string{"password", "abc", "hello"}, "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedPasswords() error = %v", err)
+	}
+
+	if !errors.Is(results[0].Err, context.DeadlineExceeded) || results[0].Status != exposed.StatusErrored {
+		t.Errorf("results[0].Err = %v, expected %v", results[0].Err, context.DeadlineExceeded)
+	}
+	for i, r := range results[1:] {
+		if r.Err != nil || r.Status != exposed.StatusNotFound {
+			t.Errorf("results[%d] = %+v, expected not found", i+1, r)
+		}
+	}
+}
+
+func TestCheckPwnedPasswordsFailFast(t *testing.T) {
+	// the range of "password" fails at once, the others respond after a
+	// delay unless canceled
+	newServer := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/5BAA6" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			select {
+			case <-r.Context().Done():
+			case <-time.After(200 * time.Millisecond):
+			}
+		}))
+	}
+	passwords := []string{"password", "abc", "hello", "letmein"}
+
+	t.Run("fail fast", func(t *testing.T) {
+		server := newServer()
+		defer server.Close()
+
+		c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+			exposed.WithConcurrency(2), exposed.WithFailFast(true))
+
+		start := time.Now()
+		results, err := c.CheckPwnedPasswords(context.Background(), passwords, "sha1")
+		if err == nil {
+			t.Fatal("CheckPwnedPasswords() expected error")
+		}
+		if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+			t.Errorf("took %v, expected the other ranges to be canceled", elapsed)
+		}
+		if len(results) != len(passwords) {
+			t.Fatalf("got %d results, expected %d", len(results), len(passwords))
+		}
+		for i, r := range results {
+			if r.Status != exposed.StatusErrored {
+				t.Errorf("results[%d] = %+v, expected errored", i, r)
+			}
+		}
+		// the other ranges are stopped by the error
+		for i, r := range results[1:] {
+			if !errors.Is(r.Err, err) {
+				t.Errorf("results[%d].Err = %v, expected %v", i+1, r.Err, err)
+			}
+		}
+	})
+
+	t.Run("fail fast one worker", func(t *testing.T) {
+		server := newServer()
+		defer server.Close()
+
+		c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+			exposed.WithConcurrency(1), exposed.WithFailFast(true))
+
+		results, err := c.CheckPwnedPasswords(context.Background(), passwords, "sha1")
+		if err == nil {
+			t.Fatal("CheckPwnedPasswords() expected error")
+		}
+		for i, r := range results {
+			if r.Status != exposed.StatusErrored {
+				t.Errorf("results[%d] = %+v, expected errored", i, r)
+			}
+			if i > 0 && !errors.Is(r.Err, err) {
+				t.Errorf("results[%d].Err = %v, expected %v", i, r.Err, err)
+			}
+		}
+
+		got := make(map[string]exposed.BatchResult)
+		err = c.CheckPwnedPasswordsFunc(context.Background(), passwords, "sha1",
+			func(input string, r exposed.BatchResult) {
+				got[input] = r
+			})
+		if err == nil {
+			t.Fatal("CheckPwnedPasswordsFunc() expected error")
+		}
+		for _, password := range passwords {
+			if r, ok := got[password]; !ok || r.Status != exposed.StatusErrored {
+				t.Errorf("result for %q = %+v, %v, expected errored", password, r, ok)
+			}
+		}
+	})
+
+	t.Run("continue", func(t *testing.T) {
+		server := newServer()
+		defer server.Close()
+
+		c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithConcurrency(2))
+
+		results, err := c.CheckPwnedPasswords(context.Background(), passwords, "sha1")
+		if err != nil {
+			t.Fatalf("CheckPwnedPasswords() error = %v", err)
+		}
+		if results[0].Status != exposed.StatusErrored {
+			t.Errorf("results[0] = %+v, expected errored", results[0])
+		}
+		for i, r := range results[1:] {
+			if r.Status != exposed.StatusNotFound {
+				t.Errorf("results[%d] = %+v, expected not found", i+1, r)
+			}
+		}
+	})
+
+	t.Run("caller cancels", func(t *testing.T) {
+		server := newServer()
+		defer server.Close()
+
+		c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithConcurrency(2))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		results, err := c.CheckPwnedPasswords(ctx, passwords, "sha1")
+		if err != nil {
+			t.Fatalf("CheckPwnedPasswords() error = %v", err)
+		}
+		for i, r := range results {
+			if !errors.Is(r.Err, context.Canceled) {
+				t.Errorf("results[%d].Err = %v, expected %v", i, r.Err, context.Canceled)
+			}
+		}
+	})
+}
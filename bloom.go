@@ -0,0 +1,208 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// bloomMagic identifies the BloomFilter serialization format.
+const bloomMagic = "EXPB"
+
+// bloomVersion is the version of the BloomFilter serialization format.
+const bloomVersion = 1
+
+// maxBloomBits and maxBloomHashes bound the size of a filter read by Load,
+// far above a filter of the full dataset, so that a corrupt header cannot
+// cause a huge allocation or endless lookups.
+const (
+	maxBloomBits   = 1 << 36 // 8 GiB
+	maxBloomHashes = 64
+)
+
+// BloomFilter is a probabilistic set of hashes for a single mode, used to
+// skip network requests for hashes that are definitely not in the Pwned
+// Passwords dataset.
+//
+// A Bloom filter has no false negatives but does have false positives: if
+// MayContain returns false, the hash was never added, but if it returns true,
+// the hash may or may not have been added. The false positive rate is chosen
+// when the filter is built and trades memory for accuracy. The full dataset
+// of roughly a billion hashes needs about 1.2 GB for a 1% rate and 1.8 GB for
+// a 0.1% rate. Because of false positives, a client only uses the filter to
+// reject hashes and always confirms a possible match with the API, so counts
+// are never taken from the filter.
+type BloomFilter struct {
+	mode string
+	k    uint32   // number of hash functions
+	m    uint64   // number of bits
+	bits []uint64 // bit array
+}
+
+// NewBloomFilter returns an empty BloomFilter for hashes of type mode sized
+// to hold n hashes with the false positive rate p.
+func NewBloomFilter(mode string, n int, p float64) (*BloomFilter, error) {
+	if _, ok := hashLengths[mode]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+	if n <= 0 {
+		return nil, fmt.Errorf("invalid number of hashes: %d", n)
+	}
+	if p <= 0 || p >= 1 {
+		return nil, fmt.Errorf("invalid false positive rate: %v", p)
+	}
+
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	m = (m + 63) / 64 * 64
+
+	return &BloomFilter{
+		mode: mode,
+		k:    k,
+		m:    m,
+		bits: make([]uint64, m/64),
+	}, nil
+}
+
+// Mode returns the mode of the hashes in the filter.
+func (b *BloomFilter) Mode() string {
+	return b.mode
+}
+
+// indexes calls fn with each bit index for hash using double hashing. The
+// hash itself is uniformly distributed, so its bytes are used directly.
+func (b *BloomFilter) indexes(hash string, fn func(i uint64) bool) error {
+	hash = strings.ToUpper(hash)
+	if err := validateHash(hash, b.mode); err != nil {
+		return err
+	}
+
+	raw, err := hex.DecodeString(hash)
+	if err != nil {
+		return err
+	}
+	h1 := binary.BigEndian.Uint64(raw[0:8])
+	h2 := binary.BigEndian.Uint64(raw[8:16]) | 1
+
+	for i := uint64(0); i < uint64(b.k); i++ {
+		if !fn((h1 + i*h2) % b.m) {
+			break
+		}
+	}
+	return nil
+}
+
+// Add adds hash to the filter.
+func (b *BloomFilter) Add(hash string) error {
+	return b.indexes(hash, func(i uint64) bool {
+		b.bits[i/64] |= 1 << (i % 64)
+		return true
+	})
+}
+
+// MayContain reports whether hash may have been added to the filter. A
+// result of false means hash was definitely not added. An invalid hash
+// reports true so that it is passed on to the API.
+func (b *BloomFilter) MayContain(hash string) bool {
+	found := true
+	err := b.indexes(hash, func(i uint64) bool {
+		found = b.bits[i/64]&(1<<(i%64)) != 0
+		return found
+	})
+	return err != nil || found
+}
+
+// WriteTo writes the filter to w in a format that can be read by Load.
+func (b *BloomFilter) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+
+	header := make([]byte, 0, len(bloomMagic)+2+len(b.mode)+12)
+	header = append(header, bloomMagic...)
+	header = append(header, bloomVersion, byte(len(b.mode)))
+	header = append(header, b.mode...)
+	header = binary.LittleEndian.AppendUint32(header, b.k)
+	header = binary.LittleEndian.AppendUint64(header, b.m)
+
+	n, err := bw.Write(header)
+	written := int64(n)
+	if err != nil {
+		return written, err
+	}
+
+	var word [8]byte
+	for _, v := range b.bits {
+		binary.LittleEndian.PutUint64(word[:], v)
+		n, err := bw.Write(word[:])
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, bw.Flush()
+}
+
+// Load replaces the contents of b with a filter read from r, as written by
+// WriteTo.
+func (b *BloomFilter) Load(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(bloomMagic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return fmt.Errorf("reading bloom filter header: %w", err)
+	}
+	if string(header[:len(bloomMagic)]) != bloomMagic {
+		return errors.New("not a bloom filter")
+	}
+	if v := header[len(bloomMagic)]; v != bloomVersion {
+		return fmt.Errorf("unsupported bloom filter version: %d", v)
+	}
+
+	rest := make([]byte, int(header[len(bloomMagic)+1])+12)
+	if _, err := io.ReadFull(br, rest); err != nil {
+		return fmt.Errorf("reading bloom filter header: %w", err)
+	}
+	mode := string(rest[:len(rest)-12])
+	k := binary.LittleEndian.Uint32(rest[len(rest)-12:])
+	m := binary.LittleEndian.Uint64(rest[len(rest)-8:])
+
+	if _, ok := hashLengths[mode]; !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+	if k == 0 || k > maxBloomHashes || m == 0 || m > maxBloomBits || m%64 != 0 {
+		return fmt.Errorf("invalid bloom filter size: k=%d, m=%d", k, m)
+	}
+
+	// grow the bits as they are read so that a truncated filter fails
+	// without allocating the size claimed by its header
+	words := m / 64
+	bits := make([]uint64, 0, min(words, 1<<16))
+	var word [8]byte
+	for uint64(len(bits)) < words {
+		if _, err := io.ReadFull(br, word[:]); err != nil {
+			return fmt.Errorf("reading bloom filter bits: %w", err)
+		}
+		bits = append(bits, binary.LittleEndian.Uint64(word[:]))
+	}
+
+	*b = BloomFilter{mode: mode, k: k, m: m, bits: bits}
+	return nil
+}
+
+// WithBloomFilter sets a BloomFilter consulted before each hash lookup of
+// the filter's mode. Hashes that are definitely not in the filter are
+// reported as not found without a network request. Possible matches are
+// checked with the API as usual. The filter must not be modified while in
+// use by the client.
+func WithBloomFilter(b *BloomFilter) Option {
+	return func(c *PwnedClient) {
+		c.bloom = b
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestBloomFilter(t *testing.T) {
+	b, err := exposed.NewBloomFilter("sha1", 1000, 0.001)
+	if err != nil {
+		t.Fatalf("NewBloomFilter() error = %v", err)
+	}
+
+	var added []string
+	for i := 0; i < 1000; i++ {
+		h := fmt.Sprintf("%040X", i*7919)
+		added = append(added, h)
+		if err := b.Add(h); err != nil {
+			t.Fatalf("Add(%q) error = %v", h, err)
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := b.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	var loaded exposed.BloomFilter
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if loaded.Mode() != "sha1" {
+		t.Errorf("Mode() = %q, expected %q", loaded.Mode(), "sha1")
+	}
+
+	for _, h := range added {
+		if !loaded.MayContain(h) {
+			t.Fatalf("MayContain(%q) = false, expected true", h)
+		}
+	}
+
+	falsePositives := 0
+	for i := 0; i < 10000; i++ {
+		if loaded.MayContain(fmt.Sprintf("F%039X", i)) {
+			falsePositives++
+		}
+	}
+	if falsePositives > 100 {
+		t.Errorf("false positives = %d of 10000, expected about 10", falsePositives)
+	}
+}
+
+func TestBloomFilterLoadInvalid(t *testing.T) {
+	header := func(k uint32, m uint64) []byte {
+		h := append([]byte("EXPB"), 1, byte(len("sha1")))
+		h = append(h, "sha1"...)
+		h = binary.LittleEndian.AppendUint32(h, k)
+		return binary.LittleEndian.AppendUint64(h, m)
+	}
+
+	valid, err := exposed.NewBloomFilter("sha1", 10, 0.01)
+	if err != nil {
+		t.Fatalf("NewBloomFilter() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := valid.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"not a filter", []byte("not a filter")},
+		{"truncated header", header(7, 64)[:12]},
+		{"oversized", header(7, 1<<62)},
+		{"too many hashes", header(1<<31, 64)},
+		// the header claims 4 GiB of bits that are not there
+		{"truncated bits", header(7, 1<<35)},
+		{"truncated filter", buf.Bytes()[:buf.Len()-1]},
+	}
+
+	for _, tc := range tests {
+		var b exposed.BloomFilter
+		if err := b.Load(bytes.NewReader(tc.data)); err == nil {
+			t.Errorf("%s: Load() error = nil, expected error", tc.name)
+		}
+	}
+}
+
+func TestWithBloomFilter(t *testing.T) {
+	b, err := exposed.NewBloomFilter("sha1", 10, 0.01)
+	if err != nil {
+		t.Fatalf("NewBloomFilter() error = %v", err)
+	}
+	if err := b.Add("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithBloomFilter(b))
+
+	count, err := c.CheckPwnedPassword("password", "sha1")
+	if err != nil || count != 10434004 {
+		t.Errorf("CheckPwnedPassword(in filter) = %v, %v, expected %v, nil", count, err, 10434004)
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, expected 1 for a possible match", requests)
+	}
+
+	count, err = c.CheckPwnedPassword("not in the filter", "sha1")
+	if err != nil || count != 0 {
+		t.Errorf("CheckPwnedPassword(not in filter) = %v, %v, expected 0, nil", count, err)
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, expected no request for a definite miss", requests)
+	}
+
+	// the filter only applies to its own mode
+	if _, err := c.CheckPwnedPassword("not in the filter", "ntlm"); err != nil {
+		t.Errorf("CheckPwnedPassword(ntlm) error = %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("requests = %d, expected a request for another mode", requests)
+	}
+}
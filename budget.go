@@ -0,0 +1,43 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrBudgetExceeded is returned for lookups that need a request after the
+// client's request budget is used up.
+var ErrBudgetExceeded = errors.New("request budget exceeded")
+
+// requestBudget counts the requests made against a maximum.
+type requestBudget struct {
+	max  int64
+	used atomic.Int64
+}
+
+// take uses one request from the budget, returning ErrBudgetExceeded if none
+// remain.
+func (b *requestBudget) take() error {
+	if b.used.Add(1) > b.max {
+		b.used.Add(-1)
+		return ErrBudgetExceeded
+	}
+	return nil
+}
+
+// WithRequestBudget limits the client to n upstream requests in total,
+// including retries, e.g., to respect a quota. Once the budget is used up,
+// lookups that need a request fail with ErrBudgetExceeded, while lookups
+// served from the cache or decided locally still succeed. If n is not
+// positive, requests are not limited.
+func WithRequestBudget(n int64) Option {
+	return func(c *PwnedClient) {
+		if n <= 0 {
+			c.budget = nil
+			return
+		}
+		c.budget = &requestBudget{max: n}
+	}
+}
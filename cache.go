@@ -0,0 +1,141 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cacheEntry is a cached range response.
+type cacheEntry struct {
+	key         string
+	body        []byte
+	contentType string
+	mirror      string // base URL that served the response
+	expires     time.Time
+}
+
+// rangeCache is a fixed-size LRU cache of range responses whose entries
+// expire after a time-to-live. It is safe for concurrent use.
+type rangeCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List // most recently used at the front
+	items map[string]*list.Element
+
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+// CacheStats reports the activity of a client's range cache.
+type CacheStats struct {
+	Hits      int64 // lookups served from the cache
+	Misses    int64 // lookups not in the cache or expired
+	Evictions int64 // entries removed to make room for new entries
+	Size      int   // entries currently in the cache
+	Capacity  int   // maximum number of entries, 0 if the cache is disabled
+}
+
+// newRangeCache returns a cache holding up to size ranges for ttl, or
+// forever if ttl is not positive.
+func newRangeCache(size int, ttl time.Duration) *rangeCache {
+	return &rangeCache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// cacheKey returns the cache key for the range of prefix and mode.
+func cacheKey(prefix, mode string) string {
+	return mode + "/" + prefix
+}
+
+// get returns the unexpired entry for key as of now.
+func (rc *rangeCache) get(key string, now time.Time) (cacheEntry, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	el, ok := rc.items[key]
+	if !ok {
+		rc.misses++
+		return cacheEntry{}, false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if rc.ttl > 0 && !now.Before(entry.expires) {
+		rc.ll.Remove(el)
+		delete(rc.items, key)
+		rc.misses++
+		return cacheEntry{}, false
+	}
+
+	rc.ll.MoveToFront(el)
+	rc.hits++
+	return *entry, true
+}
+
+// add stores entry as of now, evicting the least recently used entry if the
+// cache is full.
+func (rc *rangeCache) add(entry cacheEntry, now time.Time) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	entry.expires = now.Add(rc.ttl)
+	if el, ok := rc.items[entry.key]; ok {
+		el.Value = &entry
+		rc.ll.MoveToFront(el)
+		return
+	}
+
+	rc.items[entry.key] = rc.ll.PushFront(&entry)
+	for rc.ll.Len() > rc.size {
+		oldest := rc.ll.Back()
+		rc.ll.Remove(oldest)
+		delete(rc.items, oldest.Value.(*cacheEntry).key)
+		rc.evictions++
+	}
+}
+
+// stats returns the current cache statistics.
+func (rc *rangeCache) stats() CacheStats {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	return CacheStats{
+		Hits:      rc.hits,
+		Misses:    rc.misses,
+		Evictions: rc.evictions,
+		Size:      rc.ll.Len(),
+		Capacity:  rc.size,
+	}
+}
+
+// CacheStats returns the statistics of the client's range cache, which can
+// help choose a cache size. It returns the zero CacheStats if the cache is
+// not enabled. It is safe to call concurrently with lookups.
+func (c *PwnedClient) CacheStats() CacheStats {
+	if c.cache == nil {
+		return CacheStats{}
+	}
+	return c.cache.stats()
+}
+
+// WithCache enables an in-memory cache of up to size range responses, each
+// kept for ttl, or until evicted if ttl is not positive. Since hashes that
+// share a prefix share a range, the cache avoids repeated requests for
+// common prefixes. Entries expire according to the client's Clock.
+func WithCache(size int, ttl time.Duration) Option {
+	return func(c *PwnedClient) {
+		if size <= 0 {
+			c.cache = nil
+			return
+		}
+		c.cache = newRangeCache(size, ttl)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"context"
+	"fmt"
+)
+
+// tierFor returns the number of thresholds in tiers that count exceeds.
+// The tiers must be in strictly ascending order.
+func tierFor(count int, tiers []int) (int, error) {
+	tier := 0
+	for i, t := range tiers {
+		if i > 0 && t <= tiers[i-1] {
+			return 0, fmt.Errorf("tiers not in ascending order: %v", tiers)
+		}
+		if count > t {
+			tier = i + 1
+		}
+	}
+	return tier, nil
+}
+
+// Classify checks if the password has been exposed in breaches and returns
+// the tier its count falls into along with the count. Tiers are thresholds
+// in strictly ascending order. Tier 0 means the count does not exceed
+// tiers[0], and tier i means the count exceeds tiers[i-1] but not tiers[i].
+//
+// For example, with tiers []int{10, 1000}, a count of 5 is tier 0, a count
+// of 50 is tier 1 (e.g., warn), and a count of 5000 is tier 2 (e.g., block).
+func (c *PwnedClient) Classify(ctx context.Context, password, mode string, tiers []int) (tier int, count int, err error) {
+	// validate tiers before making a request
+	if _, err := tierFor(0, tiers); err != nil {
+		return 0, 0, err
+	}
+
+	count, err = c.CheckPwnedPasswordContext(ctx, password, mode)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tier, err = tierFor(count, tiers)
+	return tier, count, err
+}
+
+// Classify checks the password using the default client and returns the
+// tier its count falls into along with the count. See PwnedClient.Classify.
+func Classify(ctx context.Context, password, mode string, tiers []int) (tier int, count int, err error) {
+	return defaultClient().Classify(ctx, password, mode, tiers)
+}
+
+// IsPwned reports whether the password has been exposed in breaches.
+func (c *PwnedClient) IsPwned(password, mode string) (bool, error) {
+	return c.IsPwnedContext(context.Background(), password, mode)
+}
+
+// IsPwnedContext is like IsPwned but uses ctx for the request.
+func (c *PwnedClient) IsPwnedContext(ctx context.Context, password, mode string) (bool, error) {
+	return c.IsCompromisedContext(ctx, password, mode, 0)
+}
+
+// IsCompromised reports whether the password has been exposed in breaches
+// more than threshold times, consistent with the tiers of Classify.
+func (c *PwnedClient) IsCompromised(password, mode string, threshold int) (bool, error) {
+	return c.IsCompromisedContext(context.Background(), password, mode, threshold)
+}
+
+// IsCompromisedContext is like IsCompromised but uses ctx for the request.
+func (c *PwnedClient) IsCompromisedContext(ctx context.Context, password, mode string, threshold int) (bool, error) {
+	count, err := c.CheckPwnedPasswordContext(ctx, password, mode)
+	if err != nil {
+		return false, err
+	}
+	return count > threshold, nil
+}
+
+// IsPwned reports whether the password has been exposed in breaches using
+// the default client. The call has the same time limit as CheckPwned.
+func IsPwned(password, mode string) (bool, error) {
+	c, ctx, cancel := defaultCall()
+	defer cancel()
+	return c.IsPwnedContext(ctx, password, mode)
+}
+
+// IsPwnedContext is like IsPwned but uses ctx for the request instead of the
+// default time limit.
+func IsPwnedContext(ctx context.Context, password, mode string) (bool, error) {
+	return defaultClient().IsPwnedContext(ctx, password, mode)
+}
+
+// IsCompromised reports whether the password has been exposed in breaches
+// more than threshold times using the default client. The call has the same
+// time limit as CheckPwned.
+func IsCompromised(password, mode string, threshold int) (bool, error) {
+	c, ctx, cancel := defaultCall()
+	defer cancel()
+	return c.IsCompromisedContext(ctx, password, mode, threshold)
+}
+
+// IsCompromisedContext is like IsCompromised but uses ctx for the request
+// instead of the default time limit.
+func IsCompromisedContext(ctx context.Context, password, mode string, threshold int) (bool, error) {
+	return defaultClient().IsCompromisedContext(ctx, password, mode, threshold)
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name     string
+		count    int
+		tiers    []int
+		wantTier int
+		wantErr  bool
+	}{
+		{name: "not found", count: 0, tiers: []int{10, 1000}, wantTier: 0},
+		{name: "at first threshold", count: 10, tiers: []int{10, 1000}, wantTier: 0},
+		{name: "above first threshold", count: 11, tiers: []int{10, 1000}, wantTier: 1},
+		{name: "at second threshold", count: 1000, tiers: []int{10, 1000}, wantTier: 1},
+		{name: "above second threshold", count: 5000, tiers: []int{10, 1000}, wantTier: 2},
+		{name: "no tiers", count: 5000, tiers: nil, wantTier: 0},
+		{name: "unordered tiers", count: 5, tiers: []int{1000, 10}, wantErr: true},
+		{name: "duplicate tiers", count: 5, tiers: []int{10, 10}, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, "1E4C9B93F3F0682250B6CF8331B7EE68FD8:%d\n", tc.count)
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+			tier, count, err := c.Classify(context.Background(), "password", "sha1", tc.tiers)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Classify() error = %v, expectedErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			if tier != tc.wantTier {
+				t.Errorf("Classify() tier = %v, expected %v", tier, tc.wantTier)
+			}
+			if count != tc.count {
+				t.Errorf("Classify() count = %v, expected %v", count, tc.count)
+			}
+		})
+	}
+}
+
+func TestIsCompromisedContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "1E4C9B93F3F0682250B6CF8331B7EE68FD8:10")
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		fn   func() (bool, error)
+		want bool
+	}{
+		{"IsPwned", func() (bool, error) { return c.IsPwned("password", "sha1") }, true},
+		{"IsPwnedContext not found", func() (bool, error) { return c.IsPwnedContext(ctx, "other", "sha1") }, false},
+		{"IsCompromised above threshold", func() (bool, error) { return c.IsCompromised("password", "sha1", 9) }, true},
+		{"IsCompromisedContext at threshold", func() (bool, error) { return c.IsCompromisedContext(ctx, "password", "sha1", 10) }, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := tc.fn()
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %v, expected %v", got, tc.want)
+			}
+		})
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := c.IsCompromisedContext(canceled, "password", "sha1", 0); err == nil {
+		t.Error("IsCompromisedContext() with canceled context expected error")
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import "time"
+
+// Clock provides the current time and timers. It allows tests to control
+// time for retry backoff and cache expiry.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current
+	// time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is a Clock based on the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WithClock sets the Clock used for retry backoff and cache expiry. The
+// default is a Clock based on time.Now and time.After.
+func WithClock(clock Clock) Option {
+	return func(c *PwnedClient) {
+		c.clock = clock
+	}
+}
+
+// now returns the current time according to the client's Clock.
+func (c *PwnedClient) now() time.Time {
+	if c.clock == nil {
+		return time.Now()
+	}
+	return c.clock.Now()
+}
+
+// after waits for d to elapse according to the client's Clock.
+func (c *PwnedClient) after(d time.Duration) <-chan time.Time {
+	if c.clock == nil {
+		return time.After(d)
+	}
+	return c.clock.After(d)
+}
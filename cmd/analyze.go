@@ -0,0 +1,50 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/bnixon67/exposed"
+)
+
+// analysis is the JSON form of the -analyze report.
+type analysis struct {
+	Inputs   int `json:"inputs"`
+	Invalid  int `json:"invalid"`
+	Local    int `json:"local"`
+	Hashes   int `json:"hashes"`
+	Prefixes int `json:"prefixes"`
+	Requests int `json:"requests"`
+	Lookups  int `json:"lookups"`
+}
+
+// writeAnalysis writes stats to w as JSON if format is "json", otherwise as
+// text.
+func writeAnalysis(w io.Writer, stats exposed.WordlistStats, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(analysis(stats))
+	}
+
+	_, err := fmt.Fprintf(w,
+		"inputs: %d, invalid: %d, local: %d\nunique hashes: %d, unique prefixes: %d\nestimated requests: %d with a cache of %d ranges, %d without a cache\n",
+		stats.Inputs, stats.Invalid, stats.Local, stats.Hashes, stats.Prefixes,
+		stats.Requests, stats.Prefixes, stats.Lookups)
+	return err
+}
+
+// analyze reports the unique prefixes and estimated requests needed to check
+// the inputs read from r with opts, without checking them.
+func analyze(w io.Writer, r io.Reader, opts options, format string) error {
+	inputs, err := readInputs(r, opts)
+	if err != nil {
+		return fmt.Errorf("failed to read input: %w", err)
+	}
+	stats, err := opts.client.AnalyzeInputs(inputs, opts.lookup, opts.mode)
+	if err != nil {
+		return fmt.Errorf("failed to analyze input: %w", err)
+	}
+	return writeAnalysis(w, stats, format)
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestAnalyze(t *testing.T) {
+	client := exposed.NewPwnedClient(nil, "http://127.0.0.1:0")
+
+	tests := []struct {
+		name   string
+		opts   options
+		input  string
+		format string
+		want   string
+	}{
+		{
+			name:   "text",
+			input:  "password\n  password  \n\n#comment\nhello\n",
+			opts:   options{commentPrefix: "#"},
+			format: "text",
+			want:   "inputs: 3, invalid: 0, local: 0\nunique hashes: 2, unique prefixes: 2\nestimated requests: 2 with a cache of 2 ranges, 3 without a cache\n",
+		},
+		{
+			name:   "field",
+			input:  "user1,password\nuser2\nuser3,password\n",
+			opts:   options{field: 2, delimiter: ","},
+			format: "json",
+			want:   `{"inputs":2,"invalid":0,"local":0,"hashes":1,"prefixes":1,"requests":1,"lookups":2}` + "\n",
+		},
+		{
+			name:   "decoded line break",
+			input:  "pass%0Aword\npassword\n",
+			opts:   options{decode: true},
+			format: "json",
+			want:   `{"inputs":2,"invalid":0,"local":0,"hashes":2,"prefixes":2,"requests":2,"lookups":2}` + "\n",
+		},
+		{
+			name:   "empty field",
+			input:  "user1,\nuser2,password\n",
+			opts:   options{field: 2, delimiter: ","},
+			format: "json",
+			want:   `{"inputs":2,"invalid":0,"local":0,"hashes":2,"prefixes":2,"requests":2,"lookups":2}` + "\n",
+		},
+		{
+			name:   "long line",
+			input:  strings.Repeat("x", 100*1024) + "\n",
+			format: "json",
+			want:   `{"inputs":1,"invalid":0,"local":0,"hashes":1,"prefixes":1,"requests":1,"lookups":1}` + "\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := tc.opts
+			opts.client = client
+			opts.lookup, opts.mode = "password", "sha1"
+
+			var out strings.Builder
+			if err := analyze(&out, strings.NewReader(tc.input), opts, tc.format); err != nil {
+				t.Fatalf("analyze() error = %v", err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("analyze() = %q, expected %q", out.String(), tc.want)
+			}
+		})
+	}
+}
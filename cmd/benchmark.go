@@ -0,0 +1,105 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+// benchmarkStats holds the latency statistics for a benchmark run.
+type benchmarkStats struct {
+	Requests   int     `json:"requests"`
+	Errors     int     `json:"errors"`
+	MinMS      float64 `json:"min_ms"`
+	MedianMS   float64 `json:"median_ms"`
+	P95MS      float64 `json:"p95_ms"`
+	MaxMS      float64 `json:"max_ms"`
+	Throughput float64 `json:"requests_per_second"`
+}
+
+// milliseconds returns d as fractional milliseconds.
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// percentile returns the p-th percentile of the sorted latencies using the
+// nearest-rank method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(p/100*float64(len(sorted)) + 0.5)
+	if rank < 1 {
+		rank = 1
+	}
+	if rank > len(sorted) {
+		rank = len(sorted)
+	}
+	return sorted[rank-1]
+}
+
+// newBenchmarkStats computes the statistics for latencies measured over
+// elapsed.
+func newBenchmarkStats(latencies []time.Duration, errors int, elapsed time.Duration) benchmarkStats {
+	sorted := slices.Clone(latencies)
+	slices.Sort(sorted)
+
+	stats := benchmarkStats{
+		Requests: len(sorted),
+		Errors:   errors,
+	}
+	if len(sorted) > 0 {
+		stats.MinMS = milliseconds(sorted[0])
+		stats.MedianMS = milliseconds(percentile(sorted, 50))
+		stats.P95MS = milliseconds(percentile(sorted, 95))
+		stats.MaxMS = milliseconds(sorted[len(sorted)-1])
+	}
+	if elapsed > 0 {
+		stats.Throughput = float64(len(sorted)) / elapsed.Seconds()
+	}
+	return stats
+}
+
+// write writes the statistics to w as JSON if format is "json", otherwise
+// as text.
+func (s benchmarkStats) write(w io.Writer, format string) error {
+	if format == "json" {
+		return json.NewEncoder(w).Encode(s)
+	}
+
+	_, err := fmt.Fprintf(w,
+		"requests: %d, errors: %d\nlatency min: %.1fms, median: %.1fms, p95: %.1fms, max: %.1fms\nthroughput: %.1f requests/s\n",
+		s.Requests, s.Errors, s.MinMS, s.MedianMS, s.P95MS, s.MaxMS, s.Throughput)
+	return err
+}
+
+// benchmark reads inputs from r and checks each of them iterations times,
+// measuring the latency of every request. Lines that cannot be parsed are
+// reported and skipped.
+func benchmark(r io.Reader, opts options, iterations int) benchmarkStats {
+	inputs, err := readInputs(r, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "scanner error:", err)
+	}
+
+	var latencies []time.Duration
+	errors := 0
+	start := time.Now()
+	for i := 0; i < iterations; i++ {
+		for _, text := range inputs {
+			reqStart := time.Now()
+			_, err := opts.client.CheckPwned(text, opts.lookup, opts.mode)
+			latencies = append(latencies, time.Since(reqStart))
+			if err != nil {
+				errors++
+			}
+		}
+	}
+
+	return newBenchmarkStats(latencies, errors, time.Since(start))
+}
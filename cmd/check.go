@@ -0,0 +1,741 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bnixon67/exposed"
+	"golang.org/x/term"
+)
+
+// options holds the settings that control how readAndCheck parses and checks
+// each line of input.
+type options struct {
+	client    *exposed.PwnedClient
+	writer    exposed.ResultWriter
+	lookup    string // lookup type, i.e., password or hash
+	mode      string // hash mode, i.e., sha1 or ntlm
+	field     int    // 1-based field to check, or 0 for the whole line
+	delimiter string // field delimiter used when field is non-zero
+	decode    bool   // percent-decode the text before checking
+	pwdump    bool   // check the NT hash of pwdump-style lines by user
+	name      string // input name prefixed to failures, if not empty
+
+	commentPrefix string // skip lines starting with this prefix, if not empty
+	noTrim        bool   // check lines and fields without trimming whitespace
+	showPrefix    bool   // include the hash prefix sent to the API in results
+	showHash      bool   // include the hash, masked unless unmask is set
+	showSource    bool   // include the input name in results
+	explain       bool   // report how each count was derived on stderr
+	unmask        bool   // show full hashes in explanations and results
+
+	out       *bufio.Writer // buffered output of writer, if not nil
+	flushEach bool          // flush the output after each result
+
+	sample *sampler    // checks only the sampled lines, if not nil
+	strict bool        // stop at the first line that fails
+	found  *foundList  // where distinct found inputs are listed, if not nil
+	stderr io.Writer   // where failures are reported as they happen, if not nil
+	status *statusLine // running tally shown between results, if not nil
+}
+
+// flushOutput flushes the writer in opts and the buffered output beneath
+// it, if any.
+func flushOutput(opts options) error {
+	if err := opts.writer.Flush(); err != nil {
+		return err
+	}
+	if opts.out != nil {
+		return opts.out.Flush()
+	}
+	return nil
+}
+
+// trim returns s without surrounding whitespace unless opts.noTrim is set.
+func trim(s string, opts options) string {
+	if opts.noTrim {
+		return s
+	}
+	return strings.TrimSpace(s)
+}
+
+// isComment reports whether the trimmed line is a comment according to
+// opts.
+func isComment(line string, opts options) bool {
+	return opts.commentPrefix != "" && strings.HasPrefix(line, opts.commentPrefix)
+}
+
+// parseLine returns the text to check from line and the identifier to report
+// in the output. If opts.field is zero, both are the whole line. Otherwise,
+// line is split on opts.delimiter, the text is the requested field, trimmed
+// unless opts.noTrim is set, and the identifier is the first field, e.g., the
+// user in a "user:HASH" line. If the first field is itself being checked, the
+// identifier is the whole line.
+func parseLine(line string, opts options) (text, id string, err error) {
+	if opts.field == 0 {
+		return line, line, nil
+	}
+
+	fields := strings.Split(line, opts.delimiter)
+	if opts.field > len(fields) {
+		return "", line, fmt.Errorf("field %d not found, line has %d fields", opts.field, len(fields))
+	}
+
+	text = trim(fields[opts.field-1], opts)
+	id = fields[0]
+	if opts.field == 1 {
+		id = line
+	}
+	return text, id, nil
+}
+
+// inputText returns the text to check and the identifier to report for
+// line, percent-decoding the text if requested in opts. With opts.pwdump,
+// they are the NT hash and user of a pwdump-style line.
+func inputText(line string, opts options) (text, id string, err error) {
+	if opts.pwdump {
+		return parsePwdump(line)
+	}
+
+	text, id, err = parseLine(line, opts)
+	if err == nil && opts.decode {
+		text, err = url.PathUnescape(text)
+	}
+	return text, id, err
+}
+
+// maxErrors is the most per-line and write errors collected by
+// readAndCheck. The summary still counts every failure.
+const maxErrors = 100
+
+// lineError is the failure to check a single line of input.
+type lineError struct {
+	file string // input name, if reading several inputs
+	line int    // 1-based line number
+	id   string // identifier reported for the line
+	err  error
+}
+
+func (e *lineError) Error() string {
+	if e.file != "" {
+		return fmt.Sprintf("%s: line %d: failed for %q: %v", e.file, e.line, e.id, e.err)
+	}
+	return fmt.Sprintf("line %d: failed for %q: %v", e.line, e.id, e.err)
+}
+
+func (e *lineError) Unwrap() error {
+	return e.err
+}
+
+// maskSuffix returns line with the hash suffix replaced by asterisks, so the
+// full hash cannot be recovered from the line together with the prefix.
+func maskSuffix(line, suffix string) string {
+	i := strings.Index(strings.ToUpper(line), suffix)
+	if suffix == "" || i < 0 {
+		return strings.Repeat("*", len(line))
+	}
+	return line[:i] + strings.Repeat("*", len(suffix)) + line[i+len(suffix):]
+}
+
+// explanation returns how result, for the input on line, was derived: the
+// prefix queried, the line of the range response matched, and the count
+// parsed from it. The suffix in the matched line is masked unless
+// opts.unmask is set.
+func explanation(line int, result exposed.Result, opts options) string {
+	switch {
+	case result.Blocked:
+		return fmt.Sprintf("line %d: explain: matched the blocklist, no request made", line)
+	case result.Local:
+		return fmt.Sprintf("line %d: explain: not found by the allowlist or bloom filter, no request made", line)
+	case result.Line == "":
+		return fmt.Sprintf("line %d: explain: queried %s prefix %s, no line matched, count 0",
+			line, opts.mode, result.Prefix)
+	}
+
+	matched := result.Line
+	if !opts.unmask {
+		matched = maskSuffix(matched, result.Suffix)
+	}
+	return fmt.Sprintf("line %d: explain: queried %s prefix %s, matched line %q, count %d",
+		line, opts.mode, result.Prefix, matched, result.Count)
+}
+
+// logf writes a progress message to opts.stderr, if set, prefixed by the
+// input name, if any.
+func logf(opts options, format string, args ...any) {
+	opts.status.clear()
+	if opts.stderr != nil {
+		if opts.name != "" {
+			format = "%s: " + format
+			args = append([]any{opts.name}, args...)
+		}
+		fmt.Fprintf(opts.stderr, format, args...)
+	}
+}
+
+// readAndCheck reads input from an io.Reader line by line, trims any
+// surrounding whitespace from each line unless opts.noTrim is set, skips
+// blank and comment lines, and checks if the line, or the selected field of
+// the line, optionally percent-decoded, has been exposed using the client,
+// lookup, and mode in opts. Results are written with the writer in opts.
+//
+// The totals for the run are returned with the per-line and write failures,
+// up to maxErrors, and any read error joined into a single error, so callers
+// get a complete picture without parsing stderr. Failures are also
+// written to opts.stderr as they happen, if set. Reading stops early if ctx
+// is done, or at the first failed line if opts.strict is set.
+func readAndCheck(ctx context.Context, r io.Reader, opts options) (*summary, error) {
+	sum := newSummary()
+	defer sum.finish()
+
+	var errs []error
+	addError := func(err error) {
+		if len(errs) < maxErrors {
+			errs = append(errs, err)
+		}
+	}
+	addLineError := func(line int, id string, err error) {
+		sum.Errored++
+		logf(opts, "line %d: failed for %q: %v\n", line, id, err)
+		addError(&lineError{file: opts.name, line: line, id: id, err: err})
+	}
+
+	// Scan input line by line, updating the tally, if any, before waiting
+	// for each line.
+	scanner := newInputScanner(r, opts)
+	opts.status.show(sum)
+	defer opts.status.clear()
+
+	for ; ctx.Err() == nil && scanner.Scan(); opts.status.show(sum) {
+		sum.Lines++
+
+		if scanner.TooLong() {
+			sum.TooLong++
+			logf(opts, "line %d: skipped, longer than %d bytes\n", scanner.Line(), maxLineLength)
+			continue
+		}
+
+		if scanner.Blank() {
+			sum.Blank++
+			continue
+		}
+		if scanner.Comment() {
+			sum.Comments++
+			continue
+		}
+		if !opts.sample.keep() {
+			sum.SampledOut++
+			continue
+		}
+
+		text, id, err := scanner.Input()
+		if err != nil {
+			addLineError(scanner.Line(), id, err)
+			if opts.strict {
+				break
+			}
+			continue
+		}
+
+		result, err := opts.client.CheckPwnedWithResultContext(ctx, text, opts.lookup, opts.mode)
+		count := result.Count
+
+		if errors.Is(err, exposed.ErrBudgetExceeded) {
+			if sum.Skipped == 0 {
+				logf(opts, "line %d: request budget used up, skipping inputs that need a request\n", scanner.Line())
+			}
+			sum.Skipped++
+			continue
+		}
+
+		sum.Checked++
+
+		if err != nil {
+			addLineError(scanner.Line(), id, err)
+			if opts.strict {
+				break
+			}
+			continue
+		}
+
+		// a blocked input has no real count to add to the total
+		switch {
+		case result.Blocked:
+			sum.Blocked++
+		case result.Found():
+			sum.Found++
+			sum.TotalCount += count
+		default:
+			sum.NotFound++
+		}
+
+		record := exposed.Record{Input: id, Count: count, Blocked: result.Blocked}
+		if result.Blocked {
+			record.Count = 0 // BlockedCount is not a real count
+		}
+		if opts.showSource {
+			record.Source = opts.name
+		}
+		if opts.showPrefix {
+			record.Prefix = result.Prefix
+		}
+		if opts.showHash {
+			record.Hash = exposed.Redact(result.Hash)
+			if opts.unmask {
+				record.Hash = result.Hash
+			}
+		}
+		if opts.explain {
+			logf(opts, "%s\n", explanation(scanner.Line(), result, opts))
+		}
+		if result.Found() {
+			// list the hash instead of the input if asked to show hashes
+			listed := record.Input
+			if record.Hash != "" {
+				listed = record.Hash
+			}
+			if err := opts.found.add(listed); err != nil {
+				logf(opts, "write error: %v\n", err)
+				addError(fmt.Errorf("write error: %w", err))
+			}
+		}
+		opts.status.clear()
+		if err := opts.writer.WriteRecord(record); err != nil {
+			logf(opts, "write error: %v\n", err)
+			addError(fmt.Errorf("write error: %w", err))
+		}
+		if opts.flushEach {
+			if err := flushOutput(opts); err != nil {
+				logf(opts, "write error: %v\n", err)
+				addError(fmt.Errorf("write error: %w", err))
+			}
+		}
+	}
+
+	if err := flushOutput(opts); err != nil {
+		logf(opts, "write error: %v\n", err)
+		addError(fmt.Errorf("write error: %w", err))
+	}
+
+	if err := scanner.Err(); err != nil {
+		logf(opts, "scanner error: %v\n", err)
+		errs = append(errs, fmt.Errorf("scanner error: %w", err))
+	}
+
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+
+	return sum, errors.Join(errs...)
+}
+
+// newResultWriter returns a ResultWriter writing to w using tmplText, a
+// text/template, if not empty, otherwise using format.
+func newResultWriter(format, tmplText string, w io.Writer) (exposed.ResultWriter, error) {
+	if tmplText == "" {
+		return exposed.NewResultWriter(format, w)
+	}
+
+	tmpl, err := template.New("output").Parse(tmplText)
+	if err != nil {
+		return nil, fmt.Errorf("invalid template: %w", err)
+	}
+	return exposed.NewTemplateResultWriter(w, tmpl), nil
+}
+
+// runCheck runs the check command, which reads passwords or hashes and
+// reports whether each has been exposed. It is also run when no command is
+// given.
+func runCheck(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "check")
+
+	// setup flags
+	mUsage := fmt.Sprintf("mode (%s)", formatValues(exposed.ValidHashes))
+	mode := fs.String("mode", "sha1", mUsage)
+
+	lUsage := fmt.Sprintf("lookup (%s)", formatValues(exposed.ValidLookups))
+	lookup := fs.String("lookup", "password", lUsage)
+
+	fUsage := fmt.Sprintf("output format (%s)", formatValues(exposed.ResultFormats))
+	format := fs.String("format", "text", fUsage)
+
+	cUsage := fmt.Sprintf("color text results by count (%s), auto colors only on a terminal without $NO_COLOR", formatValues(colorModes))
+	color := fs.String("color", "never", cUsage)
+	eUsage := fmt.Sprintf("line ending of results (%s), crlf for Windows consumers", formatValues(eolModes))
+	eol := fs.String("eol", "lf", eUsage)
+	finalNewline := fs.Bool("final-newline", true, "end the last result with a line ending, false for consumers that reject one")
+	separator := fs.String("separator", ",", "character grouping the digits of counts in text output, e.g., \".\" or a narrow no-break space")
+	tmplText := fs.String("template", "", "write each result with a text/template `string`, e.g., \"{{.Input}} {{.Count}} {{.Found}}\", instead of -format")
+	field := fs.Int("field", 0, "1-based field of each line to check, 0 for the whole line")
+	delimiter := fs.String("delimiter", ":", "field delimiter used with -field")
+	decode := fs.Bool("decode", false, "percent-decode each input, e.g., %0A for a newline")
+	pwdump := fs.Bool("pwdump", false, "read pwdump-style \"user:rid:lmhash:nthash:::\" lines, e.g., from an Active Directory dump, and report the NT hash exposure by user, implies -lookup hash -mode ntlm")
+	sample := fs.Float64("sample", 1, "check only a random `fraction` of the inputs, from 0 to 1, e.g., 0.01 to spot-check 1% of a large list")
+	seed := fs.Int64("seed", 0, "seed for -sample so the same inputs are selected on every run, random if not set")
+	strict := fs.Bool("strict", false, "stop at the first line that cannot be checked and exit with a non-zero status")
+	watch := fs.Bool("watch", false, "show a running tally of checked and found inputs on a status line when reading from a terminal")
+	uniqOutput := fs.Bool("uniq-output", false, "collapse consecutive identical results, like uniq, into one followed by the number of repeats, every line is still checked, text output only")
+	flush := fs.Bool("flush", true, "flush the output after each result so it appears immediately, false to buffer for throughput")
+	showPrefix := fs.Bool("show-prefix", false, "include the 5 character hash prefix sent to the API in each result, never the password or full hash")
+	explain := fs.Bool("explain", false, "write how each count was derived to stderr: the prefix queried, the matched line with the hash suffix masked, and the count")
+	showHash := fs.Bool("show-hash", false, "include the hash of each input in each result to correlate with other data, masked to the prefix unless -unmask is set")
+	unmask := fs.Bool("unmask", false, "show full hashes in -explain and -show-hash output instead of masking them")
+	noTrim := fs.Bool("no-trim", false, "check lines verbatim without trimming surrounding whitespace, line endings, including CRLF, are still removed")
+	commentPrefix := fs.String("comment-prefix", "", "skip lines starting with `prefix` after trimming, e.g., \"#\", disabled if empty so every line is checked")
+
+	var files fileList
+	fs.Var(&files, "file", "read input from `path` instead of stdin, .gz, .xz, and .zst files are decompressed, may be repeated, and further paths may follow the flags, to check each in turn")
+	showSource := fs.Bool("show-source", false, "include the file each input was read from in each result, e.g., when checking several files")
+
+	allowlist := fs.String("allowlist", "", "read hashes to always report as not found from `path`, one per line")
+	blocklist := fs.String("blocklist", "", "read hashes to always report as blocked from `path`, one per line")
+
+	baseURL := fs.String("base-url", exposed.BaseURL, "base `URL` of the range API")
+	timeout := fs.Duration("timeout", 30*time.Second, "time limit for each request")
+	cacheSize := fs.Int("cache", 0, "cache up to `n` ranges in memory so repeated prefixes need no request, 0 to disable")
+	maxRequests := fs.Int64("max-requests", 0, "stop making requests after `n` upstream requests, including retries, 0 for no limit")
+	retries := fs.Int("retries", 0, "retry each failed request up to `n` times, 0 to not retry")
+	retryBase := fs.Duration("retry-base", time.Second, "delay before the first retry, doubled for each further retry")
+	rps := fs.Float64("rps", 0, "limit requests to `n` per second on average, 0 for no limit")
+	burst := fs.Int("burst", 1, "allow bursts of up to `n` requests with -rps")
+	userAgent := fs.String("user-agent", "", "User-Agent header to send, library default if empty")
+	apiKeyFile := fs.String("api-key-file", "", "read the API key from `path`, overriding $"+exposed.APIKeyEnv)
+
+	benchmarkN := fs.Int("benchmark", 0, "check each input `n` times and report request latency instead of results")
+	analyzeInputs := fs.Bool("analyze", false, "report the inputs, unique hash prefixes, and estimated requests needed to check them, e.g., to size -cache, instead of checking them")
+
+	lineCounts := fs.Bool("line-counts", false, "write the number of lines read, skipped, and checked to stderr at the end of the run")
+	foundOut := fs.String("found-out", "", "write each distinct found input to `path`, one per line without counts, e.g., for a forced reset tool, the identifier is written, such as the user with -field or -pwdump, or the hash with -show-hash, masked unless -unmask is set")
+	summaryJSON := fs.String("summary-json", "", "write a JSON summary to `path` at the end of the run, \"-\" for stderr")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *field < 0 {
+		return fmt.Errorf("invalid field: %d, must be 0 or greater", *field)
+	}
+	if *field > 0 && *delimiter == "" {
+		return errors.New("delimiter must not be empty with -field")
+	}
+
+	if *pwdump {
+		var conflicts []string
+		fs.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "field", "delimiter", "decode":
+				conflicts = append(conflicts, "-"+f.Name)
+			case "lookup":
+				if *lookup != "hash" {
+					conflicts = append(conflicts, "-lookup "+*lookup)
+				}
+			case "mode":
+				if *mode != "ntlm" {
+					conflicts = append(conflicts, "-mode "+*mode)
+				}
+			}
+		})
+		if len(conflicts) > 0 {
+			return fmt.Errorf("-pwdump cannot be used with %s", strings.Join(conflicts, ", "))
+		}
+		*lookup, *mode = "hash", "ntlm"
+	}
+
+	// validate the flags
+	validations := []struct {
+		name        string
+		value       string
+		validValues []string
+	}{
+		{"mode", *mode, exposed.ValidHashes},
+		{"lookup", *lookup, exposed.ValidLookups},
+		{"format", *format, exposed.ResultFormats},
+		{"color", *color, colorModes},
+		{"eol", *eol, eolModes},
+	}
+	for _, v := range validations {
+		valid, msg := isValid(v.name, v.value, v.validValues)
+		if !valid {
+			return errors.New(msg)
+		}
+	}
+
+	if err := validateBaseURL(*baseURL); err != nil {
+		return fmt.Errorf("invalid base-url: %w", err)
+	}
+
+	if *cacheSize < 0 {
+		return fmt.Errorf("invalid cache: %d, must be 0 or greater", *cacheSize)
+	}
+
+	if *maxRequests < 0 {
+		return fmt.Errorf("invalid max-requests: %d, must be 0 or greater", *maxRequests)
+	}
+
+	if *retries < 0 {
+		return fmt.Errorf("invalid retries: %d, must be 0 or greater", *retries)
+	}
+	if *retryBase < 0 {
+		return fmt.Errorf("invalid retry-base: %v, must be 0 or greater", *retryBase)
+	}
+
+	if *rps < 0 {
+		return fmt.Errorf("invalid rps: %v, must be 0 or greater", *rps)
+	}
+	if *burst < 1 {
+		return fmt.Errorf("invalid burst: %d, must be 1 or greater", *burst)
+	}
+
+	if *sample <= 0 || *sample > 1 {
+		return fmt.Errorf("invalid sample: %v, must be greater than 0 and at most 1", *sample)
+	}
+
+	if *benchmarkN < 0 {
+		return fmt.Errorf("invalid benchmark: %d, must be 0 or greater", *benchmarkN)
+	}
+
+	if *analyzeInputs && *benchmarkN > 0 {
+		return errors.New("-analyze cannot be used with -benchmark")
+	}
+
+	files = append(files, fs.Args()...)
+	if len(files) > 1 && (*analyzeInputs || *benchmarkN > 0) {
+		return fmt.Errorf("-analyze and -benchmark read a single input, not %d files", len(files))
+	}
+
+	if *timeout <= 0 {
+		return fmt.Errorf("invalid timeout: %v, must be positive", *timeout)
+	}
+
+	if *uniqOutput && *tmplText == "" && *format != "text" {
+		return fmt.Errorf("uniq-output requires text output, not %q", *format)
+	}
+
+	sep, size := utf8.DecodeRuneInString(*separator)
+	if sep == utf8.RuneError || size != len(*separator) {
+		return fmt.Errorf("invalid separator: %q, must be a single character", *separator)
+	}
+
+	var stdout io.Writer = os.Stdout
+	if *eol != "lf" || !*finalNewline {
+		stdout = newEOLWriter(os.Stdout, *eol == "crlf", *finalNewline)
+	}
+
+	out := bufio.NewWriter(stdout)
+	writer, err := newResultWriter(*format, *tmplText, out)
+	if err != nil {
+		return err
+	}
+	if tw, ok := writer.(*exposed.TextResultWriter); ok {
+		tw.Separator = sep
+	}
+	if *format == "text" && *tmplText == "" && useColor(*color, os.Stdout) {
+		writer = newColorResultWriter(out, sep)
+	}
+	var uniq *uniqResultWriter
+	if *uniqOutput {
+		uniq = newUniqResultWriter(writer, out)
+		writer = uniq
+	}
+
+	// open every file first so that a missing one fails the run before
+	// any results are written
+	inputs := []namedInput{{name: stdinName, r: os.Stdin}}
+	if len(files) > 0 {
+		inputs = inputs[:0]
+	}
+	for _, path := range files {
+		f, err := openInput(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		inputs = append(inputs, namedInput{name: path, r: f})
+	}
+	input := inputs[0].r
+
+	// adjust if running in a terminal session
+	if len(files) == 0 && term.IsTerminal(int(os.Stdin.Fd())) {
+		if *lookup == "password" {
+			fmt.Println("Enter passwords to check, one per line:")
+		} else {
+			fmt.Printf("Enter %s hashes to check, one per line:\n", *mode)
+		}
+	}
+
+	clientOpts := []exposed.Option{
+		exposed.WithTimeout(*timeout),
+		exposed.WithUserAgent(*userAgent),
+		exposed.WithRequestBudget(*maxRequests),
+		exposed.WithCache(*cacheSize, 0),
+		exposed.WithRetryPolicy(exposed.RetryPolicy{
+			MaxRetries: *retries,
+			BaseDelay:  *retryBase,
+		}),
+		exposed.WithRateLimit(*rps, *burst),
+	}
+	if *apiKeyFile != "" {
+		clientOpts = append(clientOpts, exposed.WithAPIKeyFile(*apiKeyFile))
+	}
+	if *allowlist != "" {
+		l, err := readHashList(*allowlist)
+		if err != nil {
+			return fmt.Errorf("invalid allowlist: %w", err)
+		}
+		clientOpts = append(clientOpts, exposed.WithAllowlist(l))
+	}
+	if *blocklist != "" {
+		l, err := readHashList(*blocklist)
+		if err != nil {
+			return fmt.Errorf("invalid blocklist: %w", err)
+		}
+		clientOpts = append(clientOpts, exposed.WithBlocklist(l))
+	}
+
+	opts := options{
+		writer:    writer,
+		out:       out,
+		flushEach: *flush,
+		client:    exposed.NewPwnedClient(nil, *baseURL, clientOpts...),
+		lookup:    *lookup,
+		mode:      *mode,
+		field:     *field,
+		delimiter: *delimiter,
+		decode:    *decode,
+		pwdump:    *pwdump,
+
+		commentPrefix: *commentPrefix,
+		noTrim:        *noTrim,
+		showPrefix:    *showPrefix,
+		showHash:      *showHash,
+		showSource:    *showSource,
+		explain:       *explain,
+		unmask:        *unmask,
+
+		strict: *strict,
+		stderr: os.Stderr,
+	}
+
+	if *sample < 1 {
+		seedSet := false
+		fs.Visit(func(f *flag.Flag) { seedSet = seedSet || f.Name == "seed" })
+		if !seedSet {
+			*seed = time.Now().UnixNano()
+		}
+		opts.sample = newSampler(*sample, *seed)
+	}
+
+	// the tally is redrawn between results, so each result must be flushed
+	// before it is redrawn
+	if *watch && len(files) == 0 && term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stderr.Fd())) {
+		opts.status = &statusLine{w: os.Stderr}
+		opts.flushEach = true
+	}
+
+	if *analyzeInputs {
+		return analyze(os.Stdout, input, opts, *format)
+	}
+
+	if *benchmarkN > 0 {
+		stats := benchmark(input, opts, *benchmarkN)
+		return stats.write(os.Stdout, *format)
+	}
+
+	if *foundOut != "" {
+		fl, err := createFoundList(*foundOut)
+		if err != nil {
+			return err
+		}
+		opts.found = fl
+	}
+
+	// stop at an interrupt like -strict does at a failure, so the results
+	// so far are still flushed and summarized
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	sum, checkErr := checkInputs(ctx, inputs, opts)
+	if opts.found != nil {
+		if err := opts.found.close(); err != nil {
+			return fmt.Errorf("failed to write found inputs: %w", err)
+		}
+	}
+	if uniq != nil {
+		if err := uniq.finish(); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+		if err := out.Flush(); err != nil {
+			return fmt.Errorf("write error: %w", err)
+		}
+	}
+	if opts.sample != nil {
+		sum.Sample = &sampleSummary{Fraction: *sample, Seed: *seed, Size: opts.sample.size}
+	}
+	sum.Cache = newCacheSummary(opts.client.CacheStats())
+	sum.Latency = newLatencySummary(opts.client.Latencies())
+	stats := opts.client.Stats()
+	sum.Retries = stats.Retries
+	sum.LimiterWaitMS = stats.LimiterWait.Milliseconds()
+
+	if *lineCounts {
+		if err := sum.writeLineCounts(os.Stderr); err != nil {
+			return fmt.Errorf("failed to write line counts: %w", err)
+		}
+	}
+
+	if *summaryJSON != "" {
+		if err := writeSummaryJSON(sum, *summaryJSON); err != nil {
+			return fmt.Errorf("failed to write summary: %w", err)
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("check interrupted: %w", err)
+	}
+
+	// per-line failures were already reported on stderr as they happened,
+	// so they only fail the run with -strict
+	var le *lineError
+	if *strict && errors.As(checkErr, &le) {
+		if le.file != "" {
+			return fmt.Errorf("stopped at the first failure, %s line %d", le.file, le.line)
+		}
+		return fmt.Errorf("stopped at the first failure, line %d", le.line)
+	}
+	return runError(checkErr)
+}
+
+// runError returns the errors joined in err by checkInputs that are not
+// per-line failures, e.g., read and write errors, which always fail the run,
+// or nil if there are none.
+func runError(err error) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		var errs []error
+		for _, e := range joined.Unwrap() {
+			if e := runError(e); e != nil {
+				errs = append(errs, e)
+			}
+		}
+		return errors.Join(errs...)
+	}
+
+	var le *lineError
+	if errors.As(err, &le) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,402 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+// countingWriter counts the writes made to it.
+type countingWriter struct {
+	strings.Builder
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Builder.Write(p)
+}
+
+func TestParseLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		opts    options
+		text    string
+		id      string
+		wantErr bool
+	}{
+		{name: "whole line", line: "user:password", text: "user:password", id: "user:password"},
+		{name: "second field", line: "user:password", opts: options{field: 2, delimiter: ":"}, text: "password", id: "user"},
+		{name: "first field", line: "password:user", opts: options{field: 1, delimiter: ":"}, text: "password", id: "password:user"},
+		{name: "comma", line: "user,pass:word", opts: options{field: 2, delimiter: ","}, text: "pass:word", id: "user"},
+		{name: "tab", line: "user	password", opts: options{field: 2, delimiter: "	"}, text: "password", id: "user"},
+		{name: "multi-character", line: "user::password", opts: options{field: 2, delimiter: "::"}, text: "password", id: "user"},
+		{name: "trimmed", line: "user: password ", opts: options{field: 2, delimiter: ":"}, text: "password", id: "user"},
+		{name: "no trim", line: "user: password ", opts: options{field: 2, delimiter: ":", noTrim: true}, text: " password ", id: "user"},
+		{name: "empty field", line: "user:", opts: options{field: 2, delimiter: ":"}, text: "", id: "user"},
+		{name: "out of range", line: "user:password", opts: options{field: 3, delimiter: ":"}, id: "user:password", wantErr: true},
+		{name: "no delimiter", line: "password", opts: options{field: 2, delimiter: ":"}, id: "password", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			text, id, err := parseLine(tc.line, tc.opts)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("parseLine(%q) error = %v, expected error %v", tc.line, err, tc.wantErr)
+			}
+			if text != tc.text || id != tc.id {
+				t.Errorf("parseLine(%q) = %q, %q, expected %q, %q", tc.line, text, id, tc.text, tc.id)
+			}
+		})
+	}
+}
+
+func TestReadAndCheckFlush(t *testing.T) {
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	for _, flushEach := range []bool{true, false} {
+		var w countingWriter
+		out := bufio.NewWriter(&w)
+		opts := options{
+			client:    client,
+			writer:    exposed.NewTextResultWriter(out),
+			lookup:    "password",
+			mode:      "sha1",
+			out:       out,
+			flushEach: flushEach,
+		}
+
+		if _, err := readAndCheck(context.Background(), strings.NewReader("password\npassword\n"), opts); err != nil {
+			t.Fatalf("flushEach %v: readAndCheck() error = %v", flushEach, err)
+		}
+
+		wantWrites := 1
+		if flushEach {
+			wantWrites = 2
+		}
+		if w.writes != wantWrites {
+			t.Errorf("flushEach %v: got %d writes, expected %d", flushEach, w.writes, wantWrites)
+		}
+		if want := "password: exposed 10,434,004 times\n"; w.String() != want+want {
+			t.Errorf("flushEach %v: output = %q", flushEach, w.String())
+		}
+	}
+}
+
+func TestReadAndCheckLowercaseHashes(t *testing.T) {
+	sha1Body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ntlmBody, err := os.ReadFile("../testdata/8846F")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{
+		SHA1: map[string]string{"5BAA6": string(sha1Body)},
+		NTLM: map[string]string{"8846F": string(ntlmBody)},
+	}
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	tests := []struct {
+		mode, input string
+	}{
+		{"sha1", "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8\n"},
+		{"ntlm", "8846f7eaee8fb117ad06bdd830b7586c\n"},
+	}
+	for _, tc := range tests {
+		var out strings.Builder
+		opts := options{
+			client: client,
+			writer: exposed.NewTextResultWriter(&out),
+			lookup: "hash",
+			mode:   tc.mode,
+		}
+
+		sum, err := readAndCheck(context.Background(), strings.NewReader(tc.input), opts)
+		if err != nil {
+			t.Fatalf("%s: readAndCheck() error = %v", tc.mode, err)
+		}
+		if sum.Found != 1 {
+			t.Errorf("%s: found %d, expected 1, output %q", tc.mode, sum.Found, out.String())
+		}
+	}
+}
+
+func TestReadAndCheckFoundList(t *testing.T) {
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	const input = "password\nunknown\npassword\nuser1,password\n"
+	tests := []struct {
+		name string
+		opts options
+		want string
+	}{
+		{"inputs", options{}, "password\n"},
+		{"identifier", options{field: 2, delimiter: ","}, "user1\n"},
+		{"masked hash", options{showHash: true}, exposed.Redact("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8") + "\n"},
+		{"unmasked hash", options{showHash: true, unmask: true}, "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n"},
+	}
+	for _, tc := range tests {
+		var out, found strings.Builder
+		opts := tc.opts
+		opts.client = client
+		opts.writer = exposed.NewTextResultWriter(&out)
+		opts.lookup = "password"
+		opts.mode = "sha1"
+		opts.found = newFoundList(&found)
+
+		_, _ = readAndCheck(context.Background(), strings.NewReader(input), opts)
+		if err := opts.found.close(); err != nil {
+			t.Fatalf("%s: close() error = %v", tc.name, err)
+		}
+		if found.String() != tc.want {
+			t.Errorf("%s: found list = %q, expected %q", tc.name, found.String(), tc.want)
+		}
+	}
+}
+
+func TestReadAndCheckErrors(t *testing.T) {
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	var out strings.Builder
+	opts := options{
+		client: client,
+		writer: exposed.NewTextResultWriter(&out),
+		lookup: "hash",
+		mode:   "sha1",
+	}
+
+	input := "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\nnot-a-hash\n\n8846F7EAEE8FB117AD06BDD830B7586C\n"
+	sum, err := readAndCheck(context.Background(), strings.NewReader(input), opts)
+
+	if sum.Lines != 4 || sum.Blank != 1 || sum.Found != 1 || sum.Errored != 2 {
+		t.Errorf("summary = %+v, expected 4 lines, 1 blank, 1 found, 2 errored", *sum)
+	}
+
+	if !errors.Is(err, exposed.ErrInvalidHash) {
+		t.Errorf("error = %v, expected ErrInvalidHash", err)
+	}
+	if !errors.Is(err, exposed.ErrModeMismatch) {
+		t.Errorf("error = %v, expected ErrModeMismatch", err)
+	}
+	for _, want := range []string{"line 2: ", "line 4: "} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, expected to contain %q", err, want)
+		}
+	}
+}
+
+func TestExplanation(t *testing.T) {
+	found := exposed.Result{
+		Prefix: "5BAA6",
+		Suffix: "1E4C9B93F3F0682250B6CF8331B7EE68FD8",
+		Count:  10434004,
+		Line:   "1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004",
+	}
+	masked := strings.Repeat("*", len(found.Suffix)) + ":10434004"
+
+	tests := []struct {
+		name   string
+		result exposed.Result
+		unmask bool
+		want   string
+	}{
+		{"found masked", found, false, `line 1: explain: queried sha1 prefix 5BAA6, matched line "` + masked + `", count 10434004`},
+		{"found unmasked", found, true, `line 1: explain: queried sha1 prefix 5BAA6, matched line "` + found.Line + `", count 10434004`},
+		{"not found", exposed.Result{Prefix: "5BAA6"}, false, "line 1: explain: queried sha1 prefix 5BAA6, no line matched, count 0"},
+		{"blocked", exposed.Result{Prefix: "5BAA6", Count: exposed.BlockedCount, Local: true, Blocked: true}, false, "line 1: explain: matched the blocklist, no request made"},
+		{"allowed", exposed.Result{Prefix: "5BAA6", Local: true}, false, "line 1: explain: not found by the allowlist or bloom filter, no request made"},
+	}
+
+	for _, tc := range tests {
+		opts := options{mode: "sha1", unmask: tc.unmask}
+		if got := explanation(1, tc.result, opts); got != tc.want {
+			t.Errorf("%s: explanation() = %q, expected %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestReadAndCheckStatus(t *testing.T) {
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	var out, status strings.Builder
+	opts := options{
+		client: client,
+		writer: exposed.NewTextResultWriter(&out),
+		lookup: "password",
+		mode:   "sha1",
+		status: &statusLine{w: &status},
+	}
+
+	if _, err := readAndCheck(context.Background(), strings.NewReader("password\n"), opts); err != nil {
+		t.Fatalf("readAndCheck() error = %v", err)
+	}
+
+	const clear = "\r\x1b[K"
+	want := clear + "checked: 0, found: 0, errored: 0" + clear +
+		clear + "checked: 1, found: 1, errored: 0" + clear
+	if status.String() != want {
+		t.Errorf("status = %q, expected %q", status.String(), want)
+	}
+}
+
+func TestReadAndCheckStrict(t *testing.T) {
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	input := "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\nnot-a-hash\n5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n"
+	for _, strict := range []bool{false, true} {
+		var out strings.Builder
+		opts := options{
+			client: client,
+			writer: exposed.NewTextResultWriter(&out),
+			lookup: "hash",
+			mode:   "sha1",
+			strict: strict,
+		}
+
+		sum, err := readAndCheck(context.Background(), strings.NewReader(input), opts)
+		if !errors.Is(err, exposed.ErrInvalidHash) {
+			t.Errorf("strict %v: error = %v, expected %v", strict, err, exposed.ErrInvalidHash)
+		}
+
+		wantLines, wantFound := 3, 2
+		if strict {
+			wantLines, wantFound = 2, 1
+		}
+		if sum.Lines != wantLines || sum.Found != wantFound {
+			t.Errorf("strict %v: read %d lines and found %d, expected %d and %d",
+				strict, sum.Lines, sum.Found, wantLines, wantFound)
+		}
+	}
+}
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRunError(t *testing.T) {
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	input := "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\nnot-a-hash\n"
+	tests := []struct {
+		name    string
+		w       io.Writer
+		wantErr bool
+	}{
+		{"line failures only", io.Discard, false},
+		{"write error", failingWriter{}, true},
+	}
+
+	for _, tc := range tests {
+		opts := options{
+			client: client,
+			writer: exposed.NewTextResultWriter(tc.w),
+			lookup: "hash",
+			mode:   "sha1",
+		}
+
+		inputs := []namedInput{
+			{name: "a", r: strings.NewReader(input)},
+			{name: "b", r: strings.NewReader(input)},
+		}
+		_, checkErr := checkInputs(context.Background(), inputs, opts)
+		if !errors.Is(checkErr, exposed.ErrInvalidHash) {
+			t.Errorf("%s: checkInputs() error = %v, expected %v", tc.name, checkErr, exposed.ErrInvalidHash)
+		}
+
+		err := runError(checkErr)
+		if (err != nil) != tc.wantErr {
+			t.Errorf("%s: runError() = %v, expected error %v", tc.name, err, tc.wantErr)
+		}
+		if errors.Is(err, exposed.ErrInvalidHash) {
+			t.Errorf("%s: runError() = %v, expected no line failures", tc.name, err)
+		}
+	}
+}
+
+func TestReadAndCheckBlocked(t *testing.T) {
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+	blocklist := exposed.NewHashList()
+	blocklist.AddPassword("acme")
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL,
+		exposed.WithBlocklist(blocklist))
+
+	var out strings.Builder
+	opts := options{
+		client: client,
+		writer: exposed.NewJSONResultWriter(&out),
+		lookup: "password",
+		mode:   "sha1",
+	}
+
+	sum, err := readAndCheck(context.Background(), strings.NewReader("password\nacme\nacme\n"), opts)
+	if err != nil {
+		t.Fatalf("readAndCheck() error = %v", err)
+	}
+	if sum.Checked != 3 || sum.Found != 1 || sum.Blocked != 2 || sum.NotFound != 0 || sum.TotalCount != 10434004 {
+		t.Errorf("summary = %+v, expected 3 checked, 1 found, 2 blocked, total count 10434004", *sum)
+	}
+
+	want := `{"input":"password","count":10434004,"found":true}` + "\n" +
+		`{"input":"acme","count":0,"found":false,"blocked":true}` + "\n" +
+		`{"input":"acme","count":0,"found":false,"blocked":true}` + "\n"
+	if out.String() != want {
+		t.Errorf("output = %q, expected %q", out.String(), want)
+	}
+
+	// the totals of several inputs do not include blocked counts either
+	total := newSummary()
+	total.add(sum)
+	total.add(sum)
+	if total.Blocked != 4 || total.TotalCount != 2*10434004 {
+		t.Errorf("total = %+v, expected 4 blocked, total count %d", *total, 2*10434004)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bnixon67/exposed"
+	"golang.org/x/term"
+)
+
+// colorModes are the valid values of the -color flag.
+var colorModes = []string{"auto", "always", "never"}
+
+// ANSI escape sequences used to color results.
+const (
+	colorGreen  = "\x1b[32m"
+	colorYellow = "\x1b[33m"
+	colorRed    = "\x1b[31m"
+	colorReset  = "\x1b[0m"
+)
+
+// colorRedMinCount is the smallest count colored red rather than yellow,
+// matching "very common" in exposed.DefaultSeverityScale.
+const colorRedMinCount = 100
+
+// useColor reports whether to color output to f for mode. With "auto",
+// color is used only if f is a terminal and NO_COLOR is not set.
+func useColor(mode string, f *os.File) bool {
+	switch mode {
+	case "always":
+		return true
+	case "auto":
+		return os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(f.Fd()))
+	default:
+		return false
+	}
+}
+
+// colorFor returns the color for r: green if not found, yellow if found,
+// and red if found at least colorRedMinCount times or blocked.
+func colorFor(r exposed.Record) string {
+	switch count := r.Count; {
+	case r.Blocked:
+		return colorRed
+	case count == 0:
+		return colorGreen
+	case count < colorRedMinCount:
+		return colorYellow
+	default:
+		return colorRed
+	}
+}
+
+// colorResultWriter writes results as text lines, like
+// exposed.TextResultWriter, colored by count.
+type colorResultWriter struct {
+	w   io.Writer
+	buf bytes.Buffer // uncolored line for the current record
+	rw  *exposed.TextResultWriter
+}
+
+// newColorResultWriter returns a colorResultWriter writing to w with counts
+// grouped by separator.
+func newColorResultWriter(w io.Writer, separator rune) *colorResultWriter {
+	cw := &colorResultWriter{w: w}
+	cw.rw = exposed.NewTextResultWriter(&cw.buf)
+	cw.rw.Separator = separator
+	return cw
+}
+
+// WriteRecord writes r colored by its count, with the line ending after the
+// color is reset.
+func (cw *colorResultWriter) WriteRecord(r exposed.Record) error {
+	cw.buf.Reset()
+	if err := cw.rw.WriteRecord(r); err != nil {
+		return err
+	}
+
+	line := bytes.TrimSuffix(cw.buf.Bytes(), []byte("\n"))
+	_, err := fmt.Fprintf(cw.w, "%s%s%s\n", colorFor(r), line, colorReset)
+	return err
+}
+
+// Flush does nothing since colorResultWriter does not buffer.
+func (cw *colorResultWriter) Flush() error {
+	return nil
+}
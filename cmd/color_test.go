@@ -0,0 +1,53 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestColorResultWriter(t *testing.T) {
+	var out strings.Builder
+	cw := newColorResultWriter(&out, ',')
+
+	records := []exposed.Record{
+		{Input: "rare", Count: 0},
+		{Input: "seen", Count: 5},
+		{Input: "password", Count: 10434004},
+		{Input: "banned", Blocked: true},
+	}
+	for _, r := range records {
+		if err := cw.WriteRecord(r); err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+	}
+
+	want := colorGreen + "rare: not found" + colorReset + "\n" +
+		colorYellow + "seen: exposed 5 times" + colorReset + "\n" +
+		colorRed + "password: exposed 10,434,004 times" + colorReset + "\n" +
+		colorRed + "banned: blocked" + colorReset + "\n"
+	if out.String() != want {
+		t.Errorf("output = %q, expected %q", out.String(), want)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// a file is not a terminal, so auto does not color
+	for mode, want := range map[string]bool{"always": true, "auto": false, "never": false} {
+		if got := useColor(mode, f); got != want {
+			t.Errorf("useColor(%q) = %v, expected %v", mode, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// parseFlags defines a -config flag on fs and parses args. If -config names
+// a file, flags not given in args are then set from it, so flags on the
+// command line override the file.
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	config := fs.String("config", "", "read flag values from the JSON `file`, e.g., {\"base-url\": \"http://localhost:8080/range\", \"rps\": 10}, flags given on the command line override it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *config == "" {
+		return nil
+	}
+	return applyConfig(fs, *config)
+}
+
+// applyConfig sets the flags of fs that were not set on the command line
+// from the JSON object in the file at path. Each key is a flag name without
+// the leading dash, and each value is a string, number, or boolean in the
+// form the flag accepts, e.g., "1h" for a duration. Unknown keys are an
+// error so that typos are not silently ignored.
+func applyConfig(fs *flag.FlagSet, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("config %s: %w", path, err)
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "config" || fs.Lookup(name) == nil {
+			return fmt.Errorf("config %s: unknown setting %q", path, name)
+		}
+		if set[name] {
+			continue
+		}
+
+		value, err := configValue(values[name])
+		if err != nil {
+			return fmt.Errorf("config %s: invalid %s: %w", path, name, err)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("config %s: invalid %s: %w", path, name, err)
+		}
+	}
+	return nil
+}
+
+// configValue returns raw, a JSON string, number, or boolean, as the text
+// to pass to flag.Value.Set.
+func configValue(raw json.RawMessage) (string, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) > 0 && raw[0] == '"' {
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	}
+
+	var v any
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return "", err
+	}
+	switch v.(type) {
+	case float64, bool:
+		return string(raw), nil
+	default:
+		return "", fmt.Errorf("%s is not a string, number, or boolean", raw)
+	}
+}
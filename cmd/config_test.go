@@ -0,0 +1,82 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFlagsConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	config := `{"base-url": "http://localhost:8080/range", "cache-size": 100, "cache-ttl": "5m", "rps": 2.5, "verbose": true}`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	baseURL := fs.String("base-url", "https://example.com", "")
+	cacheSize := fs.Int("cache-size", 10, "")
+	cacheTTL := fs.Duration("cache-ttl", time.Hour, "")
+	rps := fs.Float64("rps", 0, "")
+	verbose := fs.Bool("verbose", false, "")
+	timeout := fs.Duration("timeout", time.Second, "")
+
+	// flags on the command line override the config file
+	if err := parseFlags(fs, []string{"-config", path, "-cache-size", "7"}); err != nil {
+		t.Fatalf("parseFlags() error = %v", err)
+	}
+
+	if *baseURL != "http://localhost:8080/range" {
+		t.Errorf("base-url = %q", *baseURL)
+	}
+	if *cacheSize != 7 {
+		t.Errorf("cache-size = %d, expected command line value %d", *cacheSize, 7)
+	}
+	if *cacheTTL != 5*time.Minute {
+		t.Errorf("cache-ttl = %v, expected %v", *cacheTTL, 5*time.Minute)
+	}
+	if *rps != 2.5 {
+		t.Errorf("rps = %v, expected %v", *rps, 2.5)
+	}
+	if !*verbose {
+		t.Error("verbose = false, expected true")
+	}
+	if *timeout != time.Second {
+		t.Errorf("timeout = %v, expected default %v", *timeout, time.Second)
+	}
+}
+
+func TestParseFlagsConfigErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr string
+	}{
+		{"unknown setting", `{"base-ulr": "http://localhost"}`, `unknown setting "base-ulr"`},
+		{"invalid value", `{"cache-size": "many"}`, "invalid cache-size"},
+		{"object value", `{"cache-size": {"n": 1}}`, "invalid cache-size"},
+		{"config in config", `{"config": "other.json"}`, `unknown setting "config"`},
+		{"not an object", `[1, 2]`, "config"},
+	}
+
+	for _, tc := range tests {
+		path := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(path, []byte(tc.config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		fs := flag.NewFlagSet("test", flag.ContinueOnError)
+		fs.String("base-url", "", "")
+		fs.Int("cache-size", 0, "")
+
+		err := parseFlags(fs, []string{"-config", path})
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s: parseFlags() error = %v, expected to contain %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+// numPrefixes is the number of hex prefixes of exposed.DefaultPrefixLength
+// characters.
+const numPrefixes = 1 << (4 * exposed.DefaultPrefixLength)
+
+// progressInterval is how often download progress is reported.
+const progressInterval = 5 * time.Second
+
+// downloadStats holds the totals for a download run.
+type downloadStats struct {
+	downloaded atomic.Int64
+	skipped    atomic.Int64
+	failed     atomic.Int64
+}
+
+// done returns the number of prefixes processed so far.
+func (s *downloadStats) done() int64 {
+	return s.downloaded.Load() + s.skipped.Load() + s.failed.Load()
+}
+
+// isFresh reports whether the file at path exists and, if maxAge is
+// positive, was modified within maxAge.
+func isFresh(path string, maxAge time.Duration) bool {
+	fi, err := os.Stat(path)
+	if err != nil || !fi.Mode().IsRegular() {
+		return false
+	}
+	return maxAge <= 0 || time.Since(fi.ModTime()) < maxAge
+}
+
+// downloadPrefix downloads the range for prefix into dir, writing to a
+// temporary file first so an interrupted download never leaves a partial
+// file that would be skipped when resuming.
+func downloadPrefix(ctx context.Context, client *exposed.PwnedClient, dir, prefix, mode string) error {
+	f, err := os.CreateTemp(dir, "."+prefix+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := f.Chmod(0o644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := client.DownloadRange(ctx, prefix, mode, f); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), filepath.Join(dir, prefix))
+}
+
+// parsePrefixes returns the prefixes in the comma-separated list s, or every
+// prefix if all is true.
+func parsePrefixes(s string, all bool) ([]string, error) {
+	if all {
+		prefixes := make([]string, numPrefixes)
+		for i := range prefixes {
+			prefixes[i] = fmt.Sprintf("%0*X", exposed.DefaultPrefixLength, i)
+		}
+		return prefixes, nil
+	}
+
+	var prefixes []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.ToUpper(strings.TrimSpace(p))
+		if p == "" {
+			continue
+		}
+		if len(p) != exposed.DefaultPrefixLength || strings.Trim(p, "0123456789ABCDEF") != "" {
+			return nil, fmt.Errorf("invalid prefix: %q", p)
+		}
+		prefixes = append(prefixes, p)
+	}
+	if len(prefixes) == 0 {
+		return nil, errors.New("no prefixes given, use -prefixes or -all")
+	}
+	return prefixes, nil
+}
+
+// runDownload runs the download command, which fetches ranges into a local
+// store directory for offline use.
+func runDownload(args []string) error {
+	fs := flag.NewFlagSet("download", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "download")
+
+	mUsage := fmt.Sprintf("mode (%s)", formatValues(exposed.ValidHashes))
+	mode := fs.String("mode", "sha1", mUsage)
+	dir := fs.String("dir", "", "local store `directory` to download into")
+	prefixList := fs.String("prefixes", "", "comma-separated `list` of prefixes to download")
+	all := fs.Bool("all", false, "download all 16^5 prefixes")
+	maxAge := fs.Duration("max-age", 0, "re-download files older than this, 0 to keep existing files")
+	concurrency := fs.Int("concurrency", 8, "number of concurrent downloads")
+	rps := fs.Float64("rps", 0, "maximum requests per second, 0 for unlimited")
+	burst := fs.Int("burst", 1, "maximum burst of requests with -rps")
+	baseURL := fs.String("base-url", exposed.BaseURL, "base `URL` of the range API")
+	timeout := fs.Duration("timeout", 30*time.Second, "time limit for each request")
+	apiKeyFile := fs.String("api-key-file", "", "read the API key from `path`, overriding $"+exposed.APIKeyEnv)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if valid, msg := isValid("mode", *mode, exposed.ValidHashes); !valid {
+		return errors.New(msg)
+	}
+	if *dir == "" {
+		return errors.New("-dir is required")
+	}
+	if err := validateBaseURL(*baseURL); err != nil {
+		return fmt.Errorf("invalid base-url: %w", err)
+	}
+	if *concurrency < 1 {
+		return fmt.Errorf("invalid concurrency: %d, must be positive", *concurrency)
+	}
+	if *timeout <= 0 {
+		return fmt.Errorf("invalid timeout: %v, must be positive", *timeout)
+	}
+
+	prefixes, err := parsePrefixes(*prefixList, *all)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(*dir, 0o755); err != nil {
+		return err
+	}
+
+	clientOpts := []exposed.Option{
+		exposed.WithTimeout(*timeout),
+		exposed.WithRateLimit(*rps, *burst),
+		exposed.WithMaxIdleConnsPerHost(*concurrency),
+	}
+	if *apiKeyFile != "" {
+		clientOpts = append(clientOpts, exposed.WithAPIKeyFile(*apiKeyFile))
+	}
+	client := exposed.NewPwnedClient(nil, *baseURL, clientOpts...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var stats downloadStats
+	start := time.Now()
+
+	work := make(chan string)
+	var wg sync.WaitGroup
+	for i := 0; i < *concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for prefix := range work {
+				if err := downloadPrefix(ctx, client, *dir, prefix, *mode); err != nil {
+					stats.failed.Add(1)
+					if ctx.Err() == nil {
+						fmt.Fprintf(os.Stderr, "failed for %s: %v\n", prefix, err)
+					}
+					continue
+				}
+				stats.downloaded.Add(1)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+
+feed:
+	for _, prefix := range prefixes {
+		if isFresh(filepath.Join(*dir, prefix), *maxAge) {
+			stats.skipped.Add(1)
+			continue
+		}
+
+		select {
+		case work <- prefix:
+		case <-ticker.C:
+			fmt.Fprintf(os.Stderr, "progress: %d of %d prefixes\n", stats.done(), len(prefixes))
+			select {
+			case work <- prefix:
+			case <-ctx.Done():
+				break feed
+			}
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+
+	fmt.Fprintf(os.Stderr, "downloaded %d, skipped %d, failed %d of %d prefixes in %v\n",
+		stats.downloaded.Load(), stats.skipped.Load(), stats.failed.Load(), len(prefixes),
+		time.Since(start).Round(time.Millisecond))
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("download interrupted: %w", err)
+	}
+	if n := stats.failed.Load(); n > 0 {
+		return fmt.Errorf("%d prefixes failed", n)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"bytes"
+	"io"
+)
+
+// eolModes are the valid values of the -eol flag.
+var eolModes = []string{"lf", "crlf"}
+
+// eolWriter rewrites the line endings of the output written through it,
+// e.g., for Windows consumers, and can omit the newline ending the output.
+// Lines already ending in CRLF are not changed.
+type eolWriter struct {
+	w     io.Writer
+	crlf  bool // end lines with CRLF instead of LF
+	final bool // end the output with a newline
+
+	held   []byte // newline withheld until more output follows
+	lastCR bool   // the last byte written was a carriage return
+}
+
+// newEOLWriter returns an eolWriter writing to w.
+func newEOLWriter(w io.Writer, crlf, final bool) *eolWriter {
+	return &eolWriter{w: w, crlf: crlf, final: final}
+}
+
+// Write writes p with its line endings rewritten. Without a final newline,
+// a newline ending p is withheld until more output is written, so it is
+// never written if p ends the output.
+func (ew *eolWriter) Write(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+
+	out := make([]byte, 0, len(ew.held)+len(p)+len(p)/16+1)
+	out = append(out, ew.held...)
+	ew.held = nil
+
+	for i, b := range p {
+		if b == '\n' && ew.crlf {
+			prevCR := ew.lastCR
+			if i > 0 {
+				prevCR = p[i-1] == '\r'
+			}
+			if !prevCR {
+				out = append(out, '\r')
+			}
+		}
+		out = append(out, b)
+	}
+	ew.lastCR = p[len(p)-1] == '\r'
+
+	if !ew.final && bytes.HasSuffix(out, []byte("\n")) {
+		n := len(out) - 1
+		if bytes.HasSuffix(out[:n], []byte("\r")) {
+			n--
+		}
+		ew.held = append([]byte(nil), out[n:]...)
+		out = out[:n]
+	}
+
+	if _, err := ew.w.Write(out); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestEOLWriter(t *testing.T) {
+	tests := []struct {
+		name   string
+		crlf   bool
+		final  bool
+		writes []string
+		want   string
+	}{
+		{"lf", false, true, []string{"a\n", "b\n"}, "a\nb\n"},
+		{"crlf", true, true, []string{"a\nb\n", "c\n"}, "a\r\nb\r\nc\r\n"},
+		{"crlf unchanged", true, true, []string{"a\r\n", "b\r", "\n"}, "a\r\nb\r\n"},
+		{"no final newline", false, false, []string{"a\n", "b\n"}, "a\nb"},
+		{"no final crlf", true, false, []string{"a\n", "b\nc\n"}, "a\r\nb\r\nc"},
+		{"no final newline split write", false, false, []string{"a", "\n", "b", "\n"}, "a\nb"},
+		{"no final newline without one", false, false, []string{"a\nb"}, "a\nb"},
+		{"empty", false, false, nil, ""},
+	}
+
+	for _, tc := range tests {
+		var out strings.Builder
+		ew := newEOLWriter(&out, tc.crlf, tc.final)
+		for _, s := range tc.writes {
+			n, err := ew.Write([]byte(s))
+			if err != nil || n != len(s) {
+				t.Fatalf("%s: Write(%q) = %d, %v", tc.name, s, n, err)
+			}
+		}
+		if out.String() != tc.want {
+			t.Errorf("%s: output = %q, expected %q", tc.name, out.String(), tc.want)
+		}
+	}
+}
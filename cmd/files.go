@@ -0,0 +1,67 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// stdinName is the name of standard input in results and messages.
+const stdinName = "stdin"
+
+// fileList is a flag.Value collecting the paths of a repeated flag.
+type fileList []string
+
+func (l *fileList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *fileList) Set(path string) error {
+	if path == "" {
+		return errors.New("path must not be empty")
+	}
+	*l = append(*l, path)
+	return nil
+}
+
+// namedInput is an input to check and the name it is reported by.
+type namedInput struct {
+	name string
+	r    io.Reader
+}
+
+// checkInputs checks each of inputs in turn with readAndCheck, tagging
+// results with the input name if opts.showSource is set. With several
+// inputs, failures are prefixed by the input name, and the summary holds the
+// totals of every input with those of each in Files. Checking stops early
+// if ctx is done, or at the first failed line if opts.strict is set.
+func checkInputs(ctx context.Context, inputs []namedInput, opts options) (*summary, error) {
+	if len(inputs) == 1 {
+		if opts.showSource {
+			opts.name = inputs[0].name
+		}
+		return readAndCheck(ctx, inputs[0].r, opts)
+	}
+
+	sum := newSummary()
+	defer sum.finish()
+
+	var errs []error
+	for _, in := range inputs {
+		opts.name = in.name
+		fileSum, err := readAndCheck(ctx, in.r, opts)
+		fileSum.File = in.name
+		sum.add(fileSum)
+		sum.Files = append(sum.Files, fileSum)
+		if err != nil {
+			errs = append(errs, err)
+			if opts.strict || ctx.Err() != nil {
+				break
+			}
+		}
+	}
+	return sum, errors.Join(errs...)
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestCheckInputs(t *testing.T) {
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+	client := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	newInputs := func() []namedInput {
+		return []namedInput{
+			{name: "a.txt", r: strings.NewReader("password\n\n")},
+			{name: "b.txt", r: strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\nnot-a-hash\n")},
+			{name: "c.txt", r: strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n")},
+		}
+	}
+
+	t.Run("sources", func(t *testing.T) {
+		var out, stderr strings.Builder
+		opts := options{
+			client:     client,
+			writer:     exposed.NewTextResultWriter(&out),
+			lookup:     "hash",
+			mode:       "sha1",
+			showSource: true,
+			stderr:     &stderr,
+		}
+		inputs := newInputs()
+		inputs[0].r = strings.NewReader("5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n\n")
+
+		sum, err := checkInputs(context.Background(), inputs, opts)
+		var le *lineError
+		if !errors.As(err, &le) || le.file != "b.txt" || le.line != 2 {
+			t.Errorf("error = %v, expected b.txt line 2 to fail", err)
+		}
+
+		hash := "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+		want := "a.txt: " + hash + ": exposed 10,434,004 times\n" +
+			"b.txt: " + hash + ": exposed 10,434,004 times\n" +
+			"c.txt: " + hash + ": exposed 10,434,004 times\n"
+		if out.String() != want {
+			t.Errorf("output = %q, expected %q", out.String(), want)
+		}
+		if !strings.HasPrefix(stderr.String(), "b.txt: line 2: failed") {
+			t.Errorf("stderr = %q, expected the failure prefixed by b.txt", stderr.String())
+		}
+
+		if sum.Lines != 5 || sum.Blank != 1 || sum.Found != 3 || sum.Errored != 1 {
+			t.Errorf("summary = %+v, expected 5 lines, 1 blank, 3 found, and 1 errored", sum)
+		}
+		if len(sum.Files) != 3 || sum.Files[1].File != "b.txt" || sum.Files[1].Lines != 2 || sum.Files[1].Errored != 1 {
+			t.Errorf("file summaries = %+v, expected one per input", sum.Files)
+		}
+
+		var counts strings.Builder
+		if err := sum.writeLineCounts(&counts); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSuffix(counts.String(), "\n"), "\n")
+		if len(lines) != 4 || !strings.HasPrefix(lines[0], "a.txt: lines: 2,") || !strings.HasPrefix(lines[3], "total: lines: 5,") {
+			t.Errorf("line counts = %q, expected each file then the total", counts.String())
+		}
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		var out strings.Builder
+		opts := options{
+			client: client,
+			writer: exposed.NewTextResultWriter(&out),
+			lookup: "hash",
+			mode:   "sha1",
+			strict: true,
+		}
+		inputs := newInputs()
+		inputs[0].r = strings.NewReader("")
+
+		sum, err := checkInputs(context.Background(), inputs, opts)
+		if !errors.Is(err, exposed.ErrInvalidHash) {
+			t.Errorf("error = %v, expected %v", err, exposed.ErrInvalidHash)
+		}
+		if len(sum.Files) != 2 || sum.Found != 1 {
+			t.Errorf("summary = %+v, expected to stop in b.txt", sum)
+		}
+	})
+
+	t.Run("single input", func(t *testing.T) {
+		var out strings.Builder
+		opts := options{
+			client: client,
+			writer: exposed.NewTextResultWriter(&out),
+			lookup: "password",
+			mode:   "sha1",
+		}
+
+		sum, err := checkInputs(context.Background(), newInputs()[:1], opts)
+		if err != nil {
+			t.Fatalf("checkInputs() error = %v", err)
+		}
+		if out.String() != "password: exposed 10,434,004 times\n" || sum.File != "" || sum.Files != nil {
+			t.Errorf("output = %q, summary = %+v, expected no source", out.String(), sum)
+		}
+	})
+}
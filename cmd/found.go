@@ -0,0 +1,55 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// foundList writes each distinct found input once per line, without counts
+// or decoration, e.g., to feed a forced password reset tool.
+type foundList struct {
+	w    *bufio.Writer
+	c    io.Closer // closed by close, if not nil
+	seen map[string]bool
+}
+
+// newFoundList returns a foundList writing to w.
+func newFoundList(w io.Writer) *foundList {
+	return &foundList{w: bufio.NewWriter(w), seen: make(map[string]bool)}
+}
+
+// createFoundList creates the file at path, readable only by the owner since
+// it lists compromised inputs, and returns a foundList writing to it.
+func createFoundList(path string) (*foundList, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	fl := newFoundList(f)
+	fl.c = f
+	return fl, nil
+}
+
+// add writes s unless it was already written. A nil foundList does nothing.
+func (fl *foundList) add(s string) error {
+	if fl == nil || fl.seen[s] {
+		return nil
+	}
+	fl.seen[s] = true
+	_, err := fl.w.WriteString(s + "\n")
+	return err
+}
+
+// close flushes the list and closes the underlying file, if any.
+func (fl *foundList) close() error {
+	err := fl.w.Flush()
+	if fl.c != nil {
+		if cerr := fl.c.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
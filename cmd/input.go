@@ -0,0 +1,115 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bnixon67/exposed"
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+// gzipMagic is the header that starts every gzip stream.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// readCloser combines a reader with the closers for its underlying streams.
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+// Close closes the underlying streams, returning the first error.
+func (rc *readCloser) Close() error {
+	var first error
+	for _, c := range rc.closers {
+		if err := c.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// decoder returns a reader that decompresses r.
+type decoder func(r io.Reader) (io.ReadCloser, error)
+
+// decoders are the decompressors selected by file extension.
+var decoders = map[string]decoder{
+	".gz": func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+	".xz": func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := xz.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return io.NopCloser(zr), nil
+	},
+	".zst": func(r io.Reader) (io.ReadCloser, error) {
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	},
+}
+
+// unsupportedExts are compressed formats that cannot be decompressed.
+var unsupportedExts = []string{".7z", ".br", ".bz2", ".lz4", ".lzma", ".rar", ".zip"}
+
+// openInput opens the file at path for reading. Files with a .gz, .xz, or
+// .zst extension are transparently decompressed, as are files that start
+// with the gzip magic bytes. Other known compressed formats are rejected.
+func openInput(path string) (io.ReadCloser, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, u := range unsupportedExts {
+		if ext == u {
+			return nil, fmt.Errorf("%s: unsupported compression format %q, decompress it first", path, ext)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(f)
+	decode, ok := decoders[ext]
+	if !ok {
+		if magic, _ := br.Peek(len(gzipMagic)); bytes.Equal(magic, gzipMagic) {
+			decode = decoders[".gz"]
+		}
+	}
+	if decode == nil {
+		return &readCloser{Reader: br, closers: []io.Closer{f}}, nil
+	}
+
+	zr, err := decode(br)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &readCloser{Reader: zr, closers: []io.Closer{zr, f}}, nil
+}
+
+// readHashList reads a list of hashes from the file at path.
+func readHashList(path string) (*exposed.HashList, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l, err := exposed.ReadHashList(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return l, nil
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// maxLineLength is the longest input line, in bytes, that is checked.
+// Longer lines are skipped.
+const maxLineLength = 1024 * 1024
+
+// lineScanner reads lines like bufio.Scanner with bufio.ScanLines, but skips
+// lines longer than maxLineLength instead of stopping, so a single bad line
+// does not abort the scan of a large file.
+type lineScanner struct {
+	r       *bufio.Reader
+	text    string
+	line    int  // 1-based number of the current line
+	tooLong bool // current line exceeded maxLineLength and was skipped
+	err     error
+}
+
+// newLineScanner returns a lineScanner reading from r.
+func newLineScanner(r io.Reader) *lineScanner {
+	return &lineScanner{r: bufio.NewReaderSize(r, maxLineLength+1)}
+}
+
+// Scan advances to the next line, which is then available through Text, or
+// TooLong if it was skipped. It returns false at the end of the input or on
+// a read error.
+func (s *lineScanner) Scan() bool {
+	if s.err != nil {
+		return false
+	}
+	s.text, s.tooLong = "", false
+
+	b, err := s.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		s.line++
+		s.tooLong = true
+		s.err = s.discardLine()
+		return true
+	}
+	if err != nil && len(b) == 0 {
+		if err != io.EOF {
+			s.err = err
+		}
+		return false
+	}
+	if err != nil && err != io.EOF {
+		s.err = err
+	}
+
+	s.line++
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	b = bytes.TrimSuffix(b, []byte("\r"))
+	s.text = string(b)
+	return true
+}
+
+// discardLine reads and discards the rest of the current line.
+func (s *lineScanner) discardLine() error {
+	for {
+		_, err := s.r.ReadSlice('\n')
+		if !errors.Is(err, bufio.ErrBufferFull) {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Text returns the current line without its line ending.
+func (s *lineScanner) Text() string {
+	return s.text
+}
+
+// Line returns the 1-based number of the current line.
+func (s *lineScanner) Line() int {
+	return s.line
+}
+
+// TooLong reports whether the current line was skipped because it exceeded
+// maxLineLength.
+func (s *lineScanner) TooLong() bool {
+	return s.tooLong
+}
+
+// Err returns the first read error, if any.
+func (s *lineScanner) Err() error {
+	return s.err
+}
+
+// inputScanner reads the lines of input to check, trimmed as set by opts,
+// so readAndCheck, benchmark, and analyze skip and parse lines alike.
+type inputScanner struct {
+	*lineScanner
+	opts options
+	line string // current line, trimmed
+}
+
+// newInputScanner returns an inputScanner reading from r with opts.
+func newInputScanner(r io.Reader, opts options) *inputScanner {
+	return &inputScanner{lineScanner: newLineScanner(r), opts: opts}
+}
+
+// Scan advances to the next line like lineScanner.Scan.
+func (s *inputScanner) Scan() bool {
+	ok := s.lineScanner.Scan()
+	s.line = trim(s.lineScanner.Text(), s.opts)
+	return ok
+}
+
+// Text returns the current line, trimmed unless opts.noTrim is set.
+func (s *inputScanner) Text() string {
+	return s.line
+}
+
+// Blank reports whether the current line is empty once trimmed.
+func (s *inputScanner) Blank() bool {
+	return !s.TooLong() && s.line == ""
+}
+
+// Comment reports whether the current line is a comment.
+func (s *inputScanner) Comment() bool {
+	return !s.TooLong() && isComment(s.line, s.opts)
+}
+
+// Input returns the text to check and the identifier to report for the
+// current line, see inputText.
+func (s *inputScanner) Input() (text, id string, err error) {
+	return inputText(s.line, s.opts)
+}
+
+// readInputs returns the text to check from each line of r, parsed as
+// readAndCheck would. Lines that are skipped by readAndCheck are skipped,
+// and lines that cannot be parsed are reported and skipped. The error is
+// from reading r.
+func readInputs(r io.Reader, opts options) ([]string, error) {
+	var inputs []string
+	scanner := newInputScanner(r, opts)
+	for scanner.Scan() {
+		if scanner.TooLong() {
+			fmt.Fprintf(os.Stderr, "line %d: skipped, longer than %d bytes\n", scanner.Line(), maxLineLength)
+			continue
+		}
+		if scanner.Blank() || scanner.Comment() {
+			continue
+		}
+
+		text, id, err := scanner.Input()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "line %d: failed for %q: %v\n", scanner.Line(), id, err)
+			continue
+		}
+		inputs = append(inputs, text)
+	}
+	return inputs, scanner.Err()
+}
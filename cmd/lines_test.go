@@ -0,0 +1,179 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestLineScanner(t *testing.T) {
+	long := strings.Repeat("x", maxLineLength+10)
+	input := "first\r\n" + long + "\n\nlast"
+
+	type line struct {
+		text    string
+		tooLong bool
+	}
+	want := []line{
+		{text: "first"},
+		{tooLong: true},
+		{text: ""},
+		{text: "last"},
+	}
+
+	s := newLineScanner(strings.NewReader(input))
+	var got []line
+	for s.Scan() {
+		got = append(got, line{text: s.Text(), tooLong: s.TooLong()})
+		if s.Line() != len(got) {
+			t.Errorf("Line() = %d, expected %d", s.Line(), len(got))
+		}
+	}
+	if err := s.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d lines, expected %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("line %d = %+v, expected %+v", i+1, got[i], want[i])
+		}
+	}
+}
+
+func TestLineScannerLongLastLine(t *testing.T) {
+	s := newLineScanner(strings.NewReader("ok\n" + strings.Repeat("x", maxLineLength+1)))
+
+	var n int
+	for s.Scan() {
+		n++
+		if n == 2 && !s.TooLong() {
+			t.Error("TooLong() = false for the over-limit last line")
+		}
+	}
+	if n != 2 || s.Err() != nil {
+		t.Errorf("got %d lines, error %v, expected 2 lines and no error", n, s.Err())
+	}
+}
+
+const (
+	foundHash    = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	notFoundHash = "5BAA6FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF"
+)
+
+// checkLines checks the sha1 hashes, or passwords if opts.lookup is set, in
+// input with opts, returning the summary, text output, and error of
+// readAndCheck.
+func checkLines(t *testing.T, input string, opts options) (*summary, string, error) {
+	t.Helper()
+
+	body, err := os.ReadFile("../testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &exposed.FixtureTransport{SHA1: map[string]string{"5BAA6": string(body)}}
+
+	var out strings.Builder
+	opts.client = exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+	opts.writer = exposed.NewTextResultWriter(&out)
+	if opts.lookup == "" {
+		opts.lookup = "hash"
+	}
+	opts.mode = "sha1"
+
+	sum, err := readAndCheck(context.Background(), strings.NewReader(input), opts)
+	return sum, out.String(), err
+}
+
+func TestReadAndCheckBlankLines(t *testing.T) {
+	input := foundHash + "\n\n   \n\t\r\n" + notFoundHash + "\n\n"
+	sum, out, err := checkLines(t, input, options{})
+	if err != nil {
+		t.Fatalf("readAndCheck() error = %v", err)
+	}
+
+	if sum.Lines != 6 || sum.Blank != 4 || sum.Checked != 2 || sum.Errored != 0 {
+		t.Errorf("summary = %+v, expected 6 lines, 4 blank, 2 checked, 0 errored", *sum)
+	}
+	if got := strings.Count(out, "\n"); got != 2 {
+		t.Errorf("output = %q, expected 2 results", out)
+	}
+	if !strings.HasPrefix(out, foundHash+": ") || !strings.Contains(out, notFoundHash+": not found\n") {
+		t.Errorf("output = %q, expected a result for each hash", out)
+	}
+}
+
+func TestReadAndCheckComments(t *testing.T) {
+	input := "# found\n" + foundHash + "\n  # indented\n#" + notFoundHash + "\n" + notFoundHash + "\n"
+
+	tests := []struct {
+		name         string
+		prefix       string
+		wantComments int
+		wantChecked  int
+		wantErrored  int
+	}{
+		// without a prefix, comments are checked and fail as invalid hashes
+		{"disabled", "", 0, 5, 3},
+		{"hash", "#", 3, 2, 0},
+	}
+
+	for _, tc := range tests {
+		sum, out, err := checkLines(t, input, options{commentPrefix: tc.prefix})
+		if (err != nil) != (tc.wantErrored > 0) {
+			t.Errorf("%s: readAndCheck() error = %v", tc.name, err)
+		}
+
+		if sum.Lines != 5 || sum.Comments != tc.wantComments || sum.Checked != tc.wantChecked || sum.Errored != tc.wantErrored {
+			t.Errorf("%s: summary = %+v, expected 5 lines, %d comments, %d checked, %d errored",
+				tc.name, *sum, tc.wantComments, tc.wantChecked, tc.wantErrored)
+		}
+		if strings.Contains(out, "#") {
+			t.Errorf("%s: output = %q, expected no comments", tc.name, out)
+		}
+		if got := strings.Count(out, "\n"); got != 2 {
+			t.Errorf("%s: output = %q, expected 2 results", tc.name, out)
+		}
+	}
+}
+
+func TestReadAndCheckNoTrim(t *testing.T) {
+	// CRLF endings are removed either way, other whitespace only when
+	// trimming
+	input := "password\r\n  password\t\n \n"
+
+	tests := []struct {
+		name         string
+		noTrim       bool
+		wantBlank    int
+		wantFound    int
+		wantNotFound int
+		wantOutput   string
+	}{
+		{"trim", false, 1, 2, 0, "password: exposed 10,434,004 times\npassword: exposed 10,434,004 times\n"},
+		{"no trim", true, 0, 1, 2, "password: exposed 10,434,004 times\n  password\t: not found\n : not found\n"},
+	}
+
+	for _, tc := range tests {
+		sum, out, err := checkLines(t, input, options{lookup: "password", noTrim: tc.noTrim})
+		if err != nil {
+			t.Fatalf("%s: readAndCheck() error = %v", tc.name, err)
+		}
+
+		if sum.Lines != 3 || sum.Blank != tc.wantBlank || sum.Found != tc.wantFound || sum.NotFound != tc.wantNotFound {
+			t.Errorf("%s: summary = %+v, expected 3 lines, %d blank, %d found, %d not found",
+				tc.name, *sum, tc.wantBlank, tc.wantFound, tc.wantNotFound)
+		}
+		if out != tc.wantOutput {
+			t.Errorf("%s: output = %q, expected %q", tc.name, out, tc.wantOutput)
+		}
+	}
+}
@@ -3,101 +3,14 @@
 package main
 
 import (
-	"bufio"
-	"bytes"
 	"flag"
 	"fmt"
-	"io"
+	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
-
-	"github.com/bnixon67/exposed"
-	"golang.org/x/term"
 )
 
-// formatIntWithSeparator formats an integer with a specified single-character
-// separator, grouping the digits in threes. It supports both negative and
-// non-negative integers.
-func formatIntWithSeparator(n int, separator rune) string {
-	isNegative := n < 0
-	if isNegative {
-		n = -n
-	}
-
-	s := strconv.Itoa(n)
-	l := len(s)
-	if l <= 3 {
-		if isNegative {
-			return "-" + s
-		}
-		return s
-	}
-
-	numSeparators := (l - 1) / 3
-	bufferSize := l + numSeparators
-
-	var buf bytes.Buffer
-	buf.Grow(bufferSize)
-
-	if isNegative {
-		buf.WriteByte('-')
-	}
-
-	// Process initial segment
-	mod := l % 3
-	if mod > 0 {
-		buf.WriteString(s[:mod])
-		if l > mod {
-			buf.WriteRune(separator)
-		}
-	}
-
-	// Process remaining segments
-	for p := mod; p < l; p += 3 {
-		buf.WriteString(s[p : p+3])
-		if p+3 < l {
-			buf.WriteRune(separator)
-		}
-	}
-
-	return buf.String()
-}
-
-// readAndCheck reads input from an io.Reader line by line, trims any
-// surrounding whitespace from each line, and checks if the line has been
-// exposed using the exposed.CheckPwned function with the provided lookupMode
-// and hashMode.
-func readAndCheck(r io.Reader, lookupMode, hashMode string) {
-	// Scan input line by line.
-	scanner := bufio.NewScanner(r)
-	scanner.Split(bufio.ScanLines)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-
-		count, err := exposed.CheckPwned(line, lookupMode, hashMode)
-
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "failed for %q: %v\n", line, err)
-			continue
-		}
-
-		if count == 0 {
-			fmt.Printf("%s: not found\n", line)
-			continue
-		}
-
-		fmt.Printf("%s: exposed %s times\n",
-			line, formatIntWithSeparator(count, ','))
-	}
-
-	if err := scanner.Err(); err != nil {
-		fmt.Fprintln(os.Stderr, "scanner error:", err)
-	}
-}
-
 // formatValues takes a slice of strings and returns a single string where
 // each value is quoted and separated by a comma and space.
 //
@@ -113,44 +26,84 @@ func isValid(name, value string, validValues []string) (bool, string) {
 			return true, ""
 		}
 	}
-	return false, fmt.Sprintf("invalid %s: %q, valid values: %s\n", name, value, formatValues(validValues))
+	return false, fmt.Sprintf("invalid %s: %q, valid values: %s", name, value, formatValues(validValues))
 }
 
-func main() {
-	// setup flags
-	mUsage := fmt.Sprintf("mode (%s)", formatValues(exposed.ValidHashes))
-	mode := flag.String("mode", "sha1", mUsage)
+// validateBaseURL checks that s is an absolute http or https URL.
+func validateBaseURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must use http or https", s)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q is missing a host", s)
+	}
+	return nil
+}
+
+// command is a subcommand of the CLI.
+type command struct {
+	name        string
+	description string
+	run         func(args []string) error
+}
+
+// commands are the available subcommands. The first is run when no command
+// is given so that the bare invocation keeps working.
+var commands = []command{
+	{"check", "check passwords or hashes read from input (default)", runCheck},
+	{"serve", "run a caching proxy implementing the range API", runServe},
+	{"download", "download ranges into a local store for offline use", runDownload},
+}
 
-	lUsage := fmt.Sprintf("lookup (%s)", formatValues(exposed.ValidLookups))
-	lookup := flag.String("lookup", "password", lUsage)
-	flag.Parse()
+// progName returns the name of the program for messages.
+func progName() string {
+	return filepath.Base(os.Args[0])
+}
 
-	// validate the flags
-	validations := []struct {
-		name        string
-		value       string
-		validValues []string
-	}{
-		{"mode", *mode, exposed.ValidHashes},
-		{"lookup", *lookup, exposed.ValidLookups},
+// printCommands writes the list of commands to stderr.
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags]\n\nCommands:\n", progName())
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.description)
 	}
-	for _, v := range validations {
-		valid, msg := isValid(v.name, v.value, v.validValues)
-		if !valid {
-			fmt.Fprintf(os.Stderr, "%s: %s", filepath.Base(os.Args[0]), msg)
-			os.Exit(1)
-		}
+	fmt.Fprintf(os.Stderr, "\nRun '%s <command> -h' for the flags of a command.\n", progName())
+}
+
+// commandUsage returns a usage function for the flags of the named command.
+func commandUsage(fs *flag.FlagSet, name string) func() {
+	return func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s [flags]\n\nFlags:\n", progName(), name)
+		fs.PrintDefaults()
 	}
+}
 
-	// adjust if running in a terminal session
-	if term.IsTerminal(int(os.Stdin.Fd())) {
-		if *lookup == "password" {
-			fmt.Println("Enter passwords to check, one per line:")
-		} else {
-			fmt.Printf("Enter %s hashes to check, one per line:\n", *mode)
+// findCommand returns the command to run for args and the arguments to pass
+// to it. If args does not start with a command name, e.g., it starts with a
+// flag or an input file, the default command is returned with all of args.
+func findCommand(args []string) (command, []string) {
+	if len(args) > 0 {
+		for _, c := range commands {
+			if c.name == args[0] {
+				return c, args[1:]
+			}
 		}
+	}
+	return commands[0], args
+}
 
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "help" {
+		printCommands()
+		return
 	}
 
-	readAndCheck(os.Stdin, *lookup, *mode)
+	cmd, args := findCommand(os.Args[1:])
+	if err := cmd.run(args); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", progName(), err)
+		os.Exit(1)
+	}
 }
@@ -0,0 +1,33 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFindCommand(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantName string
+		wantArgs []string
+	}{
+		{name: "no args", args: nil, wantName: "check", wantArgs: nil},
+		{name: "flag", args: []string{"-mode", "ntlm"}, wantName: "check", wantArgs: []string{"-mode", "ntlm"}},
+		{name: "file", args: []string{"words.txt", "more.txt"}, wantName: "check", wantArgs: []string{"words.txt", "more.txt"}},
+		{name: "check", args: []string{"check", "words.txt"}, wantName: "check", wantArgs: []string{"words.txt"}},
+		{name: "serve", args: []string{"serve", "-addr", ":8080"}, wantName: "serve", wantArgs: []string{"-addr", ":8080"}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cmd, args := findCommand(tc.args)
+			if cmd.name != tc.wantName || !slices.Equal(args, tc.wantArgs) {
+				t.Errorf("findCommand(%q) = %q, %q, expected %q, %q",
+					tc.args, cmd.name, args, tc.wantName, tc.wantArgs)
+			}
+		})
+	}
+}
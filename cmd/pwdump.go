@@ -0,0 +1,42 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ntHashLength is the length of an NT hash in hex.
+const ntHashLength = 32
+
+// parsePwdump returns the NT hash and user name from a pwdump-style line,
+// "user:rid:lmhash:nthash:::", as produced when dumping Active Directory or
+// SAM credentials. Trailing fields after the NT hash are optional. The user
+// is returned when known, even with an error, so failures can be reported
+// by user without revealing the hashes on the line.
+func parsePwdump(line string) (hash, user string, err error) {
+	fields := strings.Split(line, ":")
+	if len(fields) < 4 {
+		if len(fields) > 1 {
+			user = fields[0]
+		}
+		return "", user, fmt.Errorf("malformed pwdump line, expected user:rid:lmhash:nthash:::, got %d fields", len(fields))
+	}
+
+	user, rid, hash := fields[0], fields[1], strings.TrimSpace(fields[3])
+	if user == "" {
+		return "", user, errors.New("malformed pwdump line, empty user")
+	}
+	if rid == "" || strings.IndexFunc(rid, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return "", user, fmt.Errorf("malformed pwdump line, invalid rid %q", rid)
+	}
+	if strings.HasPrefix(hash, "NO PASSWORD") {
+		return "", user, errors.New("no NT hash for user")
+	}
+	if len(hash) != ntHashLength {
+		return "", user, fmt.Errorf("malformed pwdump line, NT hash has length %d, expected %d", len(hash), ntHashLength)
+	}
+	return hash, user, nil
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePwdump(t *testing.T) {
+	const nt = "8846F7EAEE8FB117AD06BDD830B7586C"
+
+	tests := []struct {
+		name     string
+		line     string
+		wantHash string
+		wantUser string
+		wantErr  string
+	}{
+		{"full line", "alice:1001:AAD3B435B51404EEAAD3B435B51404EE:" + nt + ":::", nt, "alice", ""},
+		{"domain user", `CORP\bob:1105:aad3b435b51404eeaad3b435b51404ee:` + strings.ToLower(nt) + ":::", strings.ToLower(nt), `CORP\bob`, ""},
+		{"no trailing fields", "carol:500:AAD3B435B51404EEAAD3B435B51404EE:" + nt, nt, "carol", ""},
+		{"too few fields", "dave:1001:" + nt, "", "dave", "got 3 fields"},
+		{"bare hash", nt, "", "", "got 1 fields"},
+		{"empty user", ":1001:lm:" + nt + ":::", "", "", "empty user"},
+		{"invalid rid", "erin:x1:lm:" + nt + ":::", "", "erin", `invalid rid "x1"`},
+		{"short hash", "frank:1001:lm:8846F7:::", "", "frank", "length 6"},
+		{"no password", "grace:1001:NO PASSWORD*********************:NO PASSWORD*********************:::", "", "grace", "no NT hash"},
+	}
+
+	for _, tc := range tests {
+		hash, user, err := parsePwdump(tc.line)
+		if hash != tc.wantHash || user != tc.wantUser {
+			t.Errorf("%s: parsePwdump() = %q, %q, expected %q, %q", tc.name, hash, user, tc.wantHash, tc.wantUser)
+		}
+		if tc.wantErr == "" && err != nil {
+			t.Errorf("%s: parsePwdump() error = %v", tc.name, err)
+		}
+		if tc.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tc.wantErr)) {
+			t.Errorf("%s: parsePwdump() error = %v, expected to contain %q", tc.name, err, tc.wantErr)
+		}
+	}
+}
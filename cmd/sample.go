@@ -0,0 +1,36 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import "math/rand"
+
+// sampler selects a random fraction of inputs. The selection depends only on
+// the seed and the order of the inputs, so a run can be reproduced with the
+// same seed and input. A nil sampler selects every input.
+type sampler struct {
+	fraction float64
+	seed     int64
+	rng      *rand.Rand
+	size     int // number of inputs selected
+}
+
+// newSampler returns a sampler selecting fraction of inputs using seed.
+func newSampler(fraction float64, seed int64) *sampler {
+	return &sampler{
+		fraction: fraction,
+		seed:     seed,
+		rng:      rand.New(rand.NewSource(seed)),
+	}
+}
+
+// keep reports whether to check the next input.
+func (s *sampler) keep() bool {
+	if s == nil {
+		return true
+	}
+	if s.rng.Float64() >= s.fraction {
+		return false
+	}
+	s.size++
+	return true
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+// selected returns the indexes of the n inputs selected by s.
+func selected(s *sampler, n int) []int {
+	var idxs []int
+	for i := 0; i < n; i++ {
+		if s.keep() {
+			idxs = append(idxs, i)
+		}
+	}
+	return idxs
+}
+
+func TestSampler(t *testing.T) {
+	const n = 10000
+
+	a := selected(newSampler(0.1, 42), n)
+	b := selected(newSampler(0.1, 42), n)
+	if !slices.Equal(a, b) {
+		t.Error("samplers with the same seed selected different inputs")
+	}
+	if c := selected(newSampler(0.1, 43), n); slices.Equal(a, c) {
+		t.Error("samplers with different seeds selected the same inputs")
+	}
+
+	if got := float64(len(a)) / n; math.Abs(got-0.1) > 0.02 {
+		t.Errorf("selected %.3f of the inputs, expected about 0.1", got)
+	}
+
+	s := newSampler(1, 7)
+	if got := len(selected(s, n)); got != n || s.size != n {
+		t.Errorf("fraction 1 selected %d (size %d) of %d inputs, expected all", got, s.size, n)
+	}
+
+	var none *sampler
+	if !none.keep() {
+		t.Error("nil sampler did not keep the input")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+// shutdownTimeout is how long in-flight requests have to finish when the
+// server is asked to stop.
+const shutdownTimeout = 10 * time.Second
+
+// runServe runs the serve command, which starts an HTTP server implementing
+// the range API backed by a caching client. A readiness probe at /readyz
+// reports whether the upstream API is reachable.
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	fs.Usage = commandUsage(fs, "serve")
+
+	addr := fs.String("addr", ":8080", "`address` to listen on")
+	baseURL := fs.String("base-url", exposed.BaseURL, "base `URL` of the upstream range API")
+	timeout := fs.Duration("timeout", 30*time.Second, "time limit for each upstream request")
+	cacheSize := fs.Int("cache-size", 10000, "maximum number of ranges to cache, 0 to disable")
+	cacheTTL := fs.Duration("cache-ttl", time.Hour, "how long to cache each range, 0 for no expiry")
+	rps := fs.Float64("rps", 0, "maximum upstream requests per second, 0 for unlimited")
+	burst := fs.Int("burst", 1, "maximum burst of upstream requests with -rps")
+	apiKeyFile := fs.String("api-key-file", "", "read the API key from `path`, overriding $"+exposed.APIKeyEnv)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if err := validateBaseURL(*baseURL); err != nil {
+		return fmt.Errorf("invalid base-url: %w", err)
+	}
+	if *timeout <= 0 {
+		return fmt.Errorf("invalid timeout: %v, must be positive", *timeout)
+	}
+	if *cacheSize < 0 {
+		return fmt.Errorf("invalid cache-size: %d, must be 0 or greater", *cacheSize)
+	}
+	if *cacheTTL < 0 {
+		return fmt.Errorf("invalid cache-ttl: %v, must be 0 or greater", *cacheTTL)
+	}
+	if *rps < 0 {
+		return fmt.Errorf("invalid rps: %v, must be 0 or greater", *rps)
+	}
+	if *burst < 1 {
+		return fmt.Errorf("invalid burst: %d, must be 1 or greater", *burst)
+	}
+
+	clientOpts := []exposed.Option{
+		exposed.WithTimeout(*timeout),
+		exposed.WithCache(*cacheSize, *cacheTTL),
+		exposed.WithRateLimit(*rps, *burst),
+	}
+	if *apiKeyFile != "" {
+		clientOpts = append(clientOpts, exposed.WithAPIKeyFile(*apiKeyFile))
+	}
+	client := exposed.NewPwnedClient(nil, *baseURL, clientOpts...)
+
+	mux := http.NewServeMux()
+	mux.Handle("/range/", exposed.NewRangeHandler(client))
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := client.Ping(r.Context()); err != nil {
+			http.Error(w, "upstream not reachable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	})
+
+	server := &http.Server{
+		Addr:              *addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		log.Printf("listening on %s, upstream %s", *addr, *baseURL)
+		errc <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	log.Print("shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	stats := client.CacheStats()
+	log.Printf("cache hits %d, misses %d, evictions %d, size %d of %d",
+		stats.Hits, stats.Misses, stats.Evictions, stats.Size, stats.Capacity)
+	return nil
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+// statusLine is a running tally of the inputs checked, redrawn in place on
+// a terminal. A nil statusLine does nothing.
+type statusLine struct {
+	w     io.Writer
+	shown bool // the line is on screen and must be cleared before output
+}
+
+// show draws the tally for sum in place of the previous one.
+func (s *statusLine) show(sum *summary) {
+	if s == nil {
+		return
+	}
+	fmt.Fprintf(s.w, "\r\x1b[Kchecked: %d, found: %d, errored: %d", sum.Checked, sum.Found, sum.Errored)
+	s.shown = true
+}
+
+// clear erases the tally, if shown, so other output starts on a clean line.
+func (s *statusLine) clear() {
+	if s == nil || !s.shown {
+		return
+	}
+	fmt.Fprint(s.w, "\r\x1b[K")
+	s.shown = false
+}
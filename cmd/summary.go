@@ -0,0 +1,179 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+// summary holds the totals for a run, or for one of its inputs.
+type summary struct {
+	File       string `json:"file,omitempty"`
+	Lines      int    `json:"lines"`
+	Blank      int    `json:"blank"`
+	Comments   int    `json:"comments"`
+	TooLong    int    `json:"too_long"`
+	Checked    int    `json:"checked"`
+	Found      int    `json:"found"`
+	NotFound   int    `json:"not_found"`
+	Blocked    int    `json:"blocked"`
+	Errored    int    `json:"errored"`
+	Skipped    int    `json:"skipped_budget"`
+	SampledOut int    `json:"skipped_sample"`
+	TotalCount int    `json:"total_count"`
+	Retries    int64  `json:"retries"`
+	DurationMS int64  `json:"duration_ms"`
+
+	LimiterWaitMS int64 `json:"limiter_wait_ms"`
+
+	Sample  *sampleSummary  `json:"sample,omitempty"`
+	Cache   *cacheSummary   `json:"cache,omitempty"`
+	Latency *latencySummary `json:"latency,omitempty"`
+
+	// Files holds the totals of each input when several are read.
+	Files []*summary `json:"files,omitempty"`
+
+	start time.Time
+}
+
+// sampleSummary describes the sample checked with -sample. Size is the
+// number of inputs selected, and Seed reproduces the selection.
+type sampleSummary struct {
+	Fraction float64 `json:"fraction"`
+	Seed     int64   `json:"seed"`
+	Size     int     `json:"size"`
+}
+
+// cacheSummary holds the range cache statistics for a run.
+type cacheSummary struct {
+	Hits      int64   `json:"hits"`
+	Misses    int64   `json:"misses"`
+	Evictions int64   `json:"evictions"`
+	Size      int     `json:"size"`
+	HitRate   float64 `json:"hit_rate"`
+}
+
+// newCacheSummary returns the summary for stats, or nil if the cache is not
+// enabled.
+func newCacheSummary(stats exposed.CacheStats) *cacheSummary {
+	if stats.Capacity == 0 {
+		return nil
+	}
+
+	cs := &cacheSummary{
+		Hits:      stats.Hits,
+		Misses:    stats.Misses,
+		Evictions: stats.Evictions,
+		Size:      stats.Size,
+	}
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		cs.HitRate = float64(stats.Hits) / float64(lookups)
+	}
+	return cs
+}
+
+// latencySummary holds the upstream request latency percentiles for a run.
+// Each is the upper bound of a histogram bucket, see
+// exposed.LatencyHistogram.Percentile.
+type latencySummary struct {
+	Requests int64   `json:"requests"`
+	MeanMS   float64 `json:"mean_ms"`
+	P50MS    float64 `json:"p50_ms"`
+	P95MS    float64 `json:"p95_ms"`
+	P99MS    float64 `json:"p99_ms"`
+}
+
+// newLatencySummary returns the summary for h, or nil if no requests were
+// made.
+func newLatencySummary(h exposed.LatencyHistogram) *latencySummary {
+	if h.Count == 0 {
+		return nil
+	}
+	return &latencySummary{
+		Requests: h.Count,
+		MeanMS:   milliseconds(h.Mean()),
+		P50MS:    milliseconds(h.Percentile(50)),
+		P95MS:    milliseconds(h.Percentile(95)),
+		P99MS:    milliseconds(h.Percentile(99)),
+	}
+}
+
+// newSummary returns a summary with the run starting now.
+func newSummary() *summary {
+	return &summary{start: time.Now()}
+}
+
+// finish records the duration of the run.
+func (s *summary) finish() {
+	s.DurationMS = time.Since(s.start).Milliseconds()
+}
+
+// add adds the line and result totals of o to s.
+func (s *summary) add(o *summary) {
+	s.Lines += o.Lines
+	s.Blank += o.Blank
+	s.Comments += o.Comments
+	s.TooLong += o.TooLong
+	s.Checked += o.Checked
+	s.Found += o.Found
+	s.NotFound += o.NotFound
+	s.Blocked += o.Blocked
+	s.Errored += o.Errored
+	s.Skipped += o.Skipped
+	s.SampledOut += o.SampledOut
+	s.TotalCount += o.TotalCount
+}
+
+// writeJSON writes s as a JSON object to w.
+func (s *summary) writeJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	return enc.Encode(s)
+}
+
+// writeLineCounts writes the number of lines read, skipped, and checked to w
+// to help confirm that the input was parsed as expected. When several inputs
+// were read, the counts of each are written before the totals.
+func (s *summary) writeLineCounts(w io.Writer) error {
+	for _, f := range s.Files {
+		if _, err := fmt.Fprintf(w, "%s: ", f.File); err != nil {
+			return err
+		}
+		if err := f.writeLineCounts(w); err != nil {
+			return err
+		}
+	}
+	if len(s.Files) > 0 {
+		if _, err := io.WriteString(w, "total: "); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "lines: %d, blank skipped: %d, comments skipped: %d, too long skipped: %d, budget skipped: %d, sample skipped: %d, checked: %d, errored: %d\n",
+		s.Lines, s.Blank, s.Comments, s.TooLong, s.Skipped, s.SampledOut, s.Checked, s.Errored)
+	return err
+}
+
+// writeSummaryJSON writes s as JSON to the file at path, or to stderr if
+// path is "-".
+func writeSummaryJSON(s *summary, path string) error {
+	if path == "-" {
+		return s.writeJSON(os.Stderr)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := s.writeJSON(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
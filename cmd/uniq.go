@@ -0,0 +1,75 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bnixon67/exposed"
+)
+
+// uniqResultWriter collapses consecutive identical results, like uniq, e.g.,
+// when checking a sorted list with repeats. The first result of a run is
+// written at once by the wrapped writer, and a line with the number of
+// repeats is written to w when the run ends, i.e., when a different result
+// is written or finish is called.
+type uniqResultWriter struct {
+	next    exposed.ResultWriter
+	w       io.Writer // where repeat lines are written, beneath next
+	prev    exposed.Record
+	started bool // prev is set
+	repeats int  // results identical to prev not written
+}
+
+// newUniqResultWriter returns a uniqResultWriter that writes results with
+// next and repeat lines to w, the writer beneath next.
+func newUniqResultWriter(next exposed.ResultWriter, w io.Writer) *uniqResultWriter {
+	return &uniqResultWriter{next: next, w: w}
+}
+
+// WriteRecord writes r unless it is identical to the previous result.
+func (uw *uniqResultWriter) WriteRecord(r exposed.Record) error {
+	if uw.started && r == uw.prev {
+		uw.repeats++
+		return nil
+	}
+	if err := uw.writeRepeats(); err != nil {
+		return err
+	}
+	uw.prev, uw.started = r, true
+	return uw.next.WriteRecord(r)
+}
+
+// writeRepeats writes the number of repeats of the previous result, if any,
+// after flushing the wrapped writer so the line follows the result.
+func (uw *uniqResultWriter) writeRepeats() error {
+	if uw.repeats == 0 {
+		return nil
+	}
+	if err := uw.next.Flush(); err != nil {
+		return err
+	}
+	times := "times"
+	if uw.repeats == 1 {
+		times = "time"
+	}
+	_, err := fmt.Fprintf(uw.w, "... repeated %d more %s\n", uw.repeats, times)
+	uw.repeats = 0
+	return err
+}
+
+// Flush flushes the wrapped writer. The repeats of the current run are not
+// known yet, so they are not written.
+func (uw *uniqResultWriter) Flush() error {
+	return uw.next.Flush()
+}
+
+// finish writes the repeats of the last run, if any, and flushes the wrapped
+// writer.
+func (uw *uniqResultWriter) finish() error {
+	if err := uw.writeRepeats(); err != nil {
+		return err
+	}
+	return uw.next.Flush()
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Bill Nixon
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestUniqResultWriter(t *testing.T) {
+	var out strings.Builder
+	uw := newUniqResultWriter(exposed.NewTextResultWriter(&out), &out)
+
+	records := []exposed.Record{
+		{Input: "abc", Count: 5},
+		{Input: "abc", Count: 5},
+		{Input: "abc", Count: 5},
+		{Input: "password", Count: 10434004},
+		{Input: "password", Count: 10434004},
+		{Input: "abc", Count: 5},
+		{Input: "rare", Count: 0},
+		{Input: "rare", Count: 0},
+	}
+	for _, r := range records {
+		if err := uw.WriteRecord(r); err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+		// flushing after each result must not end a run
+		if err := uw.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+	}
+	if err := uw.finish(); err != nil {
+		t.Fatalf("finish() error = %v", err)
+	}
+
+	want := "abc: exposed 5 times\n" +
+		"... repeated 2 more times\n" +
+		"password: exposed 10,434,004 times\n" +
+		"... repeated 1 more time\n" +
+		"abc: exposed 5 times\n" +
+		"rare: not found\n" +
+		"... repeated 1 more time\n"
+	if out.String() != want {
+		t.Errorf("output = %q, expected %q", out.String(), want)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestCheckPwnedContextCanceled(t *testing.T) {
+	tests := []struct {
+		name   string
+		text   string
+		lookup string
+	}{
+		{
+			name:   "password",
+			text:   "password",
+			lookup: "password",
+		},
+		{
+			name:   "hash",
+			text:   "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+			lookup: "hash",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			started := make(chan struct{})
+			release := make(chan struct{})
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				select {
+				case <-r.Context().Done():
+				case <-release:
+				}
+			}))
+			defer server.Close()
+			defer close(release)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				<-started
+				cancel()
+			}()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+			start := time.Now()
+			_, err := c.CheckPwnedContext(ctx, tc.text, tc.lookup, "sha1")
+			elapsed := time.Since(start)
+
+			if !errors.Is(err, context.Canceled) {
+				t.Errorf("CheckPwnedContext() error = %v, expected %v", err, context.Canceled)
+			}
+			if elapsed > 5*time.Second {
+				t.Errorf("CheckPwnedContext() took %v after cancel", elapsed)
+			}
+		})
+	}
+}
+
+func TestCheckPwnedContextCanceledDuringRetry(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithRetryPolicy(exposed.RetryPolicy{MaxRetries: 1, BaseDelay: time.Hour}))
+
+	start := time.Now()
+	_, err := c.CheckPwnedPasswordContext(ctx, "password", "sha1")
+	elapsed := time.Since(start)
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("CheckPwnedPasswordContext() error = %v, expected %v", err, context.DeadlineExceeded)
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf("CheckPwnedPasswordContext() took %v after deadline", elapsed)
+	}
+}
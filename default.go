@@ -0,0 +1,74 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// defaultClientPtr is the client used by the package-level functions, or
+// nil for DefaultPwnedClient.
+var defaultClientPtr atomic.Pointer[PwnedClient]
+
+// defaultClient returns the client used by the package-level functions.
+func defaultClient() *PwnedClient {
+	if c := defaultClientPtr.Load(); c != nil {
+		return c
+	}
+	return &DefaultPwnedClient
+}
+
+// SetDefaultClient sets the client used by the package-level functions, such
+// as CheckPwned. It is safe to call concurrently with those functions, since
+// clients are never modified once in use: calls in progress finish with the
+// previous client and later calls use c. If c is nil, DefaultPwnedClient is
+// used again.
+func SetDefaultClient(c *PwnedClient) {
+	defaultClientPtr.Store(c)
+}
+
+// Configure replaces the client used by the package-level functions with a
+// new client for BaseURL configured with opts. It is safe to call
+// concurrently with those functions. See SetDefaultClient.
+func Configure(opts ...Option) {
+	SetDefaultClient(NewPwnedClient(nil, BaseURL, opts...))
+}
+
+// DefaultCallTimeout limits a call to a package-level function that does not
+// take a context, such as CheckPwned, if the default client has no HTTP
+// timeout.
+const DefaultCallTimeout = 2 * time.Minute
+
+// callTimeout returns the time limit for a package-level call without a
+// context: the HTTP client's timeout for each attempt allowed by the retry
+// policy, or DefaultCallTimeout if the HTTP client has no timeout. Retry
+// delays are not included, since a retry that cannot finish in time is not
+// worth waiting for.
+func (c *PwnedClient) callTimeout() time.Duration {
+	if c.httpClient.Timeout <= 0 {
+		return DefaultCallTimeout
+	}
+	return c.httpClient.Timeout * time.Duration(1+max(c.retryPolicy.MaxRetries, 0))
+}
+
+// defaultCall returns the default client and a context limited by its
+// callTimeout for a package-level call without a context, so the call cannot
+// hang indefinitely, e.g., by stacking retries or rate limit waits. Use the
+// Context variant of a function to set a different deadline.
+func defaultCall() (*PwnedClient, context.Context, context.CancelFunc) {
+	return defaultCallContext(context.Background())
+}
+
+// defaultCallContext is like defaultCall for a package-level call that takes
+// ctx but has no variant without a context, such as AnyModePwned. The
+// callTimeout applies only if ctx has no deadline of its own.
+func defaultCallContext(ctx context.Context) (*PwnedClient, context.Context, context.CancelFunc) {
+	c := defaultClient()
+	if _, ok := ctx.Deadline(); ok {
+		return c, ctx, func() {}
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.callTimeout())
+	return c, ctx, cancel
+}
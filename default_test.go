@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+// TestSetDefaultClientConcurrent replaces the default client while other
+// goroutines use it. Run with -race to detect unsafe access.
+func TestSetDefaultClientConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+	defer exposed.SetDefaultClient(nil)
+
+	exposed.SetDefaultClient(exposed.NewPwnedClient(&http.Client{}, server.URL))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			count, err := exposed.CheckPwned("password", "password", "sha1")
+			if err != nil {
+				t.Errorf("CheckPwned() error = %v", err)
+			}
+			if count != 10434004 {
+				t.Errorf("CheckPwned() = %d, expected %d", count, 10434004)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			exposed.SetDefaultClient(exposed.NewPwnedClient(&http.Client{}, server.URL,
+				exposed.WithUserAgent("test")))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCheckPwnedDefaultDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+	defer exposed.SetDefaultClient(nil)
+
+	// the second call would wait 10s for the rate limiter, far longer than
+	// the 100ms HTTP timeout allows
+	exposed.SetDefaultClient(exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithTimeout(100*time.Millisecond),
+		exposed.WithRateLimit(0.1, 1)))
+
+	if _, err := exposed.CheckPwned("password", "password", "sha1"); err != nil {
+		t.Fatalf("CheckPwned() error = %v", err)
+	}
+
+	start := time.Now()
+	if _, err := exposed.CheckPwned("password", "password", "sha1"); err == nil {
+		t.Error("CheckPwned() error = nil, expected the default deadline to be exceeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("CheckPwned() took %v, expected to stop at the default deadline", elapsed)
+	}
+}
+
+func TestAllModesDefaultDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+	defer exposed.SetDefaultClient(nil)
+
+	tests := []struct {
+		name string
+		call func() error
+	}{
+		{"CheckPwnedAllModes", func() error {
+			_, err := exposed.CheckPwnedAllModes("password", "password")
+			return err
+		}},
+		{"AnyModePwned", func() error {
+			_, _, err := exposed.AnyModePwned(context.Background(), "password", "password")
+			return err
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			// the first call uses up the rate limiter, so the lookups
+			// of the next would wait 10s, far longer than the 100ms
+			// HTTP timeout allows
+			exposed.SetDefaultClient(exposed.NewPwnedClient(&http.Client{}, server.URL,
+				exposed.WithTimeout(100*time.Millisecond),
+				exposed.WithRateLimit(0.1, 1)))
+			if _, err := exposed.CheckPwned("password", "password", "sha1"); err != nil {
+				t.Fatalf("CheckPwned() error = %v", err)
+			}
+
+			start := time.Now()
+			if err := tc.call(); err == nil {
+				t.Errorf("%s() error = nil, expected the default deadline to be exceeded", tc.name)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("%s() took %v, expected to stop at the default deadline", tc.name, elapsed)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/bnixon67/exposed"
+)
+
+// This example checks a password and a hash against canned ranges served
+// from memory by a FixtureTransport, so it runs without network access. To
+// check against the Pwned Passwords API, pass nil instead of the HTTP
+// client.
+func ExamplePwnedClient_CheckPwned() {
+	transport := &exposed.FixtureTransport{
+		SHA1: map[string]string{
+			"5BAA6": "1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\r\n" +
+				"1E4C9B93F3F0682250B6CF8331B7EE68FD9:0\r\n",
+		},
+	}
+	c := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	count, err := c.CheckPwned("password", "password", "sha1")
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	fmt.Printf("password: exposed %d times\n", count)
+
+	// hashes are matched regardless of case
+	count, err = c.CheckPwned("5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "hash", "sha1")
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	fmt.Printf("hash: exposed %d times\n", count)
+
+	// prefixes without a fixture are empty ranges
+	count, err = c.CheckPwned("correct horse battery staple", "password", "sha1")
+	if err != nil {
+		fmt.Println("error:", err)
+		return
+	}
+	fmt.Printf("passphrase: exposed %d times\n", count)
+
+	// Output:
+	// password: exposed 10434004 times
+	// hash: exposed 10434004 times
+	// passphrase: exposed 0 times
+}
@@ -8,60 +8,272 @@ package exposed
 
 import (
 	"bufio"
+	"context"
 	"crypto/sha1"
 	"encoding/binary"
 	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"log/slog"
+	"mime"
 	"net/http"
 	"net/url"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+	"unicode"
 	"unicode/utf16"
 
 	"golang.org/x/crypto/md4"
+	"golang.org/x/time/rate"
 )
 
 // BaseURL is the endpoint for the Pwned Passwords API.
 const BaseURL = "https://api.pwnedpasswords.com/range"
 
+// DefaultUserAgent is the User-Agent header sent when none is configured.
+const DefaultUserAgent = "exposed (+https://github.com/bnixon67/exposed)"
+
+// ErrTruncatedResponse is returned when a response body ends before the
+// length given by its Content-Length header.
+var ErrTruncatedResponse = errors.New("truncated response body")
+
+// ErrResponseTooLarge is returned when a response body exceeds the maximum
+// size set by WithResponseSizeLimit.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// DefaultResponseSizeLimit is the default maximum size of a range response.
+// Real ranges, including padding, are well under 100 KB.
+const DefaultResponseSizeLimit = 1 << 20
+
+// ErrInvalidMode is returned when a mode is not one of ValidHashes.
+var ErrInvalidMode = errors.New("invalid mode")
+
+// ErrInvalidHash is returned when a hash is not valid for its mode.
+var ErrInvalidHash = errors.New("invalid hash")
+
+// ErrModeMismatch is returned when a hash is valid for a mode other than the
+// one requested, e.g., a SHA-1 hash looked up with mode ntlm.
+var ErrModeMismatch = errors.New("hash does not match mode")
+
 var ValidHashes = []string{"sha1", "ntlm"}
 var ValidLookups = []string{"password", "hash"}
 
+// hashLengths is the length of the hex encoded hash for each mode.
+var hashLengths = map[string]int{
+	"sha1": 40,
+	"ntlm": 32,
+}
+
+// validateHash checks that hash is an uppercase hex string of the length
+// expected for mode.
+func validateHash(hash, mode string) error {
+	want, ok := hashLengths[mode]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+	if len(hash) != want {
+		for other, length := range hashLengths {
+			if len(hash) == length {
+				return fmt.Errorf("%w: %w: %d characters is a %s hash, not %s",
+					ErrInvalidHash, ErrModeMismatch, len(hash), other, mode)
+			}
+		}
+		return fmt.Errorf("%w: %s hash must be %d hex characters, got %d", ErrInvalidHash, mode, want, len(hash))
+	}
+	if i := strings.IndexFunc(hash, notUpperHex); i >= 0 {
+		return fmt.Errorf("%w: %s hash contains non-hex character %q", ErrInvalidHash, mode, hash[i])
+	}
+	return nil
+}
+
+// CanonicalizeHash returns hash in the canonical form used by the API,
+// uppercase without any whitespace, e.g., from a hash pasted with spaces,
+// line breaks, or mixed case. It returns an error wrapping ErrInvalidHash if
+// the result is not a valid hash for mode, or ErrInvalidMode if mode is not
+// valid. The Check methods canonicalize hashes with it.
+func CanonicalizeHash(hash, mode string) (string, error) {
+	hash = strings.ToUpper(stripSpace(hash))
+	if err := validateHash(hash, mode); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// stripSpace returns s without any Unicode whitespace.
+func stripSpace(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// notUpperHex reports whether r is not an uppercase hex digit.
+func notUpperHex(r rune) bool {
+	return !('0' <= r && r <= '9' || 'A' <= r && r <= 'F')
+}
+
+// Result is the outcome of checking a hash.
+type Result struct {
+	Hash   string // full hash checked, uppercase hex
+	Prefix string // hash prefix sent to the API
+	Suffix string // matched hash suffix, empty if not found
+	Count  int    // number of times exposed, 0 if not found
+
+	// Line is the line of the range response the count was parsed from,
+	// verbatim, or the matching entry re-encoded for a JSON response. It
+	// is empty if not found. It contains the suffix, so it reveals the full
+	// hash together with Prefix.
+	Line string
+
+	// Local reports whether the result was decided without a request, by
+	// the blocklist, allowlist, or bloom filter.
+	Local bool
+
+	// Blocked reports whether the hash matched the client's blocklist, in
+	// which case Count is BlockedCount and no request was made.
+	Blocked bool
+
+	// Mirror is the base URL that served the range, e.g., a fallback set
+	// with WithMirrors, or the one that originally served a cached range.
+	// It is empty if Local is set.
+	Mirror string
+}
+
+// Found reports whether the hash was exposed in breaches, so callers need
+// not treat a Count of 0 specially. A matched padding entry, which has a
+// Line but a Count of 0, is not found. A hash matching the blocklist is
+// found.
+func (r Result) Found() bool {
+	return r.Count > 0
+}
+
 // PwnedClient is a client to checkif passwords or hashes have been exposed.
 type PwnedClient struct {
 	httpClient *http.Client
 	baseURL    string
+	mirrors    []string // fallback base URLs, see WithMirrors
+	method     string   // HTTP method for range requests, GET if empty
+	accept     string   // Accept header for range requests, omitted if empty
+	userAgent  string   // User-Agent header, DefaultUserAgent if empty
+
+	mirrorStrategy MirrorStrategy
+	mirrorNext     atomic.Uint64 // start of the next range for MirrorRoundRobin
+
+	paddingHeader string // DefaultPaddingHeader if empty
+	paddingValue  string // DefaultPaddingValue if paddingHeader is empty
+
+	notFoundAsEmpty bool // treat 404 range responses as empty ranges
+
+	apiKey     string // hibp-api-key header, omitted if empty
+	apiKeyFile string // file to read apiKey from
+
+	retryPolicy     RetryPolicy
+	transport       transportConfig
+	sharedTransport *http.Transport // used as is if not nil, see WithSharedTransport
+	clock           Clock           // nil uses the time package
+
+	normalization Normalization
+	bloom         *BloomFilter
+	allowlist     *HashList
+	blocklist     *HashList
+	cache         *rangeCache
+	limiter       *rate.Limiter
+	budget        *requestBudget
+	stats         *clientStats // nil to not count
+	logger        *slog.Logger // wraps a redactHandler, nil to not log
+
+	concurrency int           // DefaultConcurrency if not positive
+	rampUp      time.Duration // no ramp-up if not positive
+	failFast    bool          // stop a batch at the first range fetch error
+
+	maxResponseSize int64 // DefaultResponseSizeLimit if not positive
+	prefixLength    int   // DefaultPrefixLength if not positive
+
+	// configErr is the first error from an option or the environment,
+	// returned by every request since options cannot fail
+	configErr error
 }
 
-// NewPwnedClient creates a new PwnedClient with given HTTP client and base URL.
-func NewPwnedClient(client *http.Client, baseURL string) *PwnedClient {
-	return &PwnedClient{
+// PwnedChecker is the interface implemented by PwnedClient for checking
+// passwords and hashes. Code that depends on a PwnedChecker instead of a
+// *PwnedClient can be tested with a fake implementation.
+type PwnedChecker interface {
+	CheckPwned(text, lookup, mode string) (int, error)
+	CheckPwnedHash(hash, mode string) (int, error)
+	CheckPwnedPassword(password, mode string) (int, error)
+}
+
+var _ PwnedChecker = (*PwnedClient)(nil)
+
+// NewPwnedClient creates a new PwnedClient with given HTTP client, base URL,
+// and options. If client is nil, an HTTP client with the same settings as
+// DefaultPwnedClient is used. If no API key option is given, the key is read
+// from the environment, see APIKeyEnv and APIKeyFileEnv.
+func NewPwnedClient(client *http.Client, baseURL string, opts ...Option) *PwnedClient {
+	if client == nil {
+		client = newDefaultHTTPClient()
+	}
+
+	c := &PwnedClient{
 		httpClient: client,
 		baseURL:    baseURL,
+		stats:      new(clientStats),
+	}
+	for _, opt := range opts {
+		opt(c)
 	}
+	c.resolveAPIKey()
+	c.applyTransportConfig()
+	return c
 }
 
-var DefaultPwnedClient = PwnedClient{
-	httpClient: &http.Client{
-		Timeout: 30 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:          100,
-			IdleConnTimeout:       90 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
+// setConfigErr records err as the configuration error of the client unless
+// one was already recorded.
+func (c *PwnedClient) setConfigErr(err error) {
+	if c.configErr == nil {
+		c.configErr = err
+	}
+}
+
+// newDefaultTransport returns the transport used by DefaultPwnedClient.
+func newDefaultTransport() *http.Transport {
+	return &http.Transport{
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+// newDefaultHTTPClient returns the HTTP client used by DefaultPwnedClient.
+func newDefaultHTTPClient() *http.Client {
+	return &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: newDefaultTransport(),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			if len(via) >= 10 {
 				return errors.New("stopped after 10 redirects")
 			}
 			return nil
 		},
-	},
-	baseURL: BaseURL,
+	}
+}
+
+// DefaultPwnedClient is the client used by the package-level functions until
+// Configure or SetDefaultClient is called. It must not be modified while in
+// use; replace the default client with SetDefaultClient instead.
+var DefaultPwnedClient = PwnedClient{
+	httpClient: newDefaultHTTPClient(),
+	baseURL:    BaseURL,
+	stats:      new(clientStats),
 }
 
 // extractCount returns the breach count from a line.
@@ -74,14 +286,14 @@ func extractCount(line string) (int, error) {
 	return strconv.Atoi(count)
 }
 
-// buildURL builds the URL for the API request.
-func buildURL(baseURL, hash, mode string) (*url.URL, error) {
+// buildURL builds the URL for the API request for the range of prefix.
+func buildURL(baseURL, prefix, mode string) (*url.URL, error) {
 	u, err := url.Parse(baseURL)
 	if err != nil {
 		return nil, err
 	}
 
-	u.Path = path.Join(u.Path, hash[:5])
+	u.Path = path.Join(u.Path, prefix)
 	if mode == "ntlm" {
 		query := u.Query()
 		query.Set("mode", mode)
@@ -90,16 +302,46 @@ func buildURL(baseURL, hash, mode string) (*url.URL, error) {
 	return u, nil
 }
 
-// newGetRequestWithPadding creates an HTTP GET request for the given URL,
-// setting the Add-Padding header to enhance privacy.
-func newGetRequestWithPadding(u *url.URL) (*http.Request, error) {
-	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+// newRequestWithPadding creates an HTTP request with method for the given
+// URL, setting the padding header name to value to enhance privacy.
+func newRequestWithPadding(ctx context.Context, method string, u *url.URL, name, value string) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
 	if err != nil {
 		return nil, err
 	}
 
 	// pads out responds to enhance privacy with additional zero results
-	req.Header.Set("Add-Padding", "true")
+	req.Header.Set(name, value)
+
+	return req, nil
+}
+
+// newRangeRequest creates a range request for u with the headers configured
+// for the client.
+func (c *PwnedClient) newRangeRequest(ctx context.Context, u *url.URL) (*http.Request, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
+
+	name, value := c.padding()
+	req, err := newRequestWithPadding(ctx, c.requestMethod(), u, name, value)
+	if err != nil {
+		return nil, err
+	}
+
+	userAgent := c.userAgent
+	if userAgent == "" {
+		userAgent = DefaultUserAgent
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	if c.accept != "" {
+		req.Header.Set("Accept", c.accept)
+	}
+
+	if c.apiKey != "" {
+		req.Header.Set(apiKeyHeader, c.apiKey)
+	}
 
 	return req, nil
 }
@@ -121,22 +363,140 @@ func findLineWithPrefix(r io.Reader, prefix string) (string, error) {
 	return "", nil // ignore io.EOF
 }
 
-// processResponse processes body and extracts the breach count.
-func processResponse(body io.Reader, hash string) (int, error) {
-	suffix := hash[5:]
+// lengthReader reads from r and reports ErrTruncatedResponse if r ends before
+// want bytes have been read.
+type lengthReader struct {
+	r    io.Reader
+	want int64
+	read int64
+}
+
+func (lr *lengthReader) Read(p []byte) (int, error) {
+	n, err := lr.r.Read(p)
+	lr.read += int64(n)
+	if (err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF)) && lr.read < lr.want {
+		return n, fmt.Errorf("%w: read %d of %d bytes", ErrTruncatedResponse, lr.read, lr.want)
+	}
+	return n, err
+}
+
+// sizeLimitReader reads from r and reports ErrResponseTooLarge once more
+// than limit bytes have been read.
+type sizeLimitReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (sr *sizeLimitReader) Read(p []byte) (int, error) {
+	if sr.read >= sr.limit {
+		// probe for data beyond the limit
+		var b [1]byte
+		n, err := sr.r.Read(b[:])
+		if n > 0 {
+			return 0, fmt.Errorf("%w: more than %d bytes", ErrResponseTooLarge, sr.limit)
+		}
+		return 0, err
+	}
+
+	if int64(len(p)) > sr.limit-sr.read {
+		p = p[:sr.limit-sr.read]
+	}
+	n, err := sr.r.Read(p)
+	sr.read += int64(n)
+	return n, err
+}
+
+// responseBody returns the body of resp limited to the client's maximum
+// response size, checking that the full body is read if the length of the
+// body is known.
+func (c *PwnedClient) responseBody(resp *http.Response) io.Reader {
+	limit := c.maxResponseSize
+	if limit <= 0 {
+		limit = DefaultResponseSizeLimit
+	}
+	if resp.ContentLength > limit {
+		return errReader{fmt.Errorf("%w: Content-Length %d exceeds %d bytes", ErrResponseTooLarge, resp.ContentLength, limit)}
+	}
+
+	var r io.Reader = &sizeLimitReader{r: resp.Body, limit: limit}
+	if resp.ContentLength >= 0 {
+		r = &lengthReader{r: r, want: resp.ContentLength}
+	}
+	return r
+}
+
+// errReader is an io.Reader that always returns err.
+type errReader struct {
+	err error
+}
+
+func (er errReader) Read([]byte) (int, error) {
+	return 0, er.err
+}
+
+// processJSONResponse decodes body as a JSON object mapping suffixes to
+// counts and returns the result for the suffix of hash after the first
+// prefixLen characters.
+func processJSONResponse(body io.Reader, hash string, prefixLen int) (Result, error) {
+	result := Result{Hash: hash, Prefix: hash[:prefixLen]}
+
+	var counts map[string]int
+	if err := json.NewDecoder(body).Decode(&counts); err != nil {
+		// an empty or whitespace-only body has no matches
+		if err == io.EOF {
+			return result, nil
+		}
+		return result, fmt.Errorf("invalid JSON range: %w", err)
+	}
+
+	suffix := hash[prefixLen:]
+	for s, count := range counts {
+		if strings.EqualFold(s, suffix) {
+			result.Suffix = strings.ToUpper(s)
+			result.Count = count
+			result.Line = fmt.Sprintf("%q:%d", s, count)
+			return result, nil
+		}
+	}
+	return result, nil
+}
+
+// processResponse processes body and extracts the result for hash, whose
+// first prefixLen characters are the prefix of the range. The body
+// is decoded as JSON if contentType is application/json, otherwise it is
+// parsed as colon-delimited lines. An empty or whitespace-only body, as
+// returned when nothing matches and padding is disabled, is not found
+// rather than an error in either format.
+func processResponse(body io.Reader, contentType, hash string, prefixLen int) (Result, error) {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "application/json" {
+		return processJSONResponse(body, hash, prefixLen)
+	}
+
+	result := Result{Hash: hash, Prefix: hash[:prefixLen]}
+
+	suffix := hash[prefixLen:]
 	line, err := findLineWithPrefix(body, suffix)
 	if err != nil {
-		return 0, err
+		return result, err
 	}
 	if line == "" {
-		return 0, nil
+		return result, nil
 	}
-	return extractCount(line)
+
+	count, err := extractCount(line)
+	if err != nil {
+		return result, err
+	}
+	result.Suffix = suffix
+	result.Count = count
+	result.Line = line
+	return result, nil
 }
 
-// ntHash computes the NT hash of s and returns it as an uppercase
-// hex string.
-func ntHash(s string) string {
+// ntDigest computes the NT hash of s, the MD4 digest of s encoded as
+// UTF-16 Little Endian.
+func ntDigest(s string) []byte {
 	// Convert s to UTF-16 Little Endian
 	runes := utf16.Encode([]rune(s))
 	b := make([]byte, len(runes)*2)
@@ -146,76 +506,341 @@ func ntHash(s string) string {
 
 	hash := md4.New()
 	hash.Write(b)
-	return strings.ToUpper(hex.EncodeToString(hash.Sum(nil)))
+	return hash.Sum(nil)
+}
+
+// ntHash computes the NT hash of s and returns it as an uppercase
+// hex string.
+func ntHash(s string) string {
+	return strings.ToUpper(hex.EncodeToString(ntDigest(s)))
+}
+
+// sha1Digest computes the SHA-1 hash of s.
+func sha1Digest(s string) []byte {
+	hash := sha1.Sum([]byte(s))
+	return hash[:]
 }
 
 // sha1Hash computes the SHA-1 hash of s and returns it as an uppercase
 // hex string.
 func sha1Hash(s string) string {
-	hash := sha1.Sum([]byte(s))
-	return strings.ToUpper(hex.EncodeToString(hash[:]))
+	return strings.ToUpper(hex.EncodeToString(sha1Digest(s)))
 }
 
 // CheckPwnedHash checks if the hash of type mode has been exposed in breaches.
+// The hash is canonicalized with CanonicalizeHash, so case and whitespace do
+// not matter. The mode determines both the expected length of the hash and
+// the query sent to the API, so a hash that is not valid for mode is an
+// error.
 func (c *PwnedClient) CheckPwnedHash(hash, mode string) (int, error) {
-	hash = strings.ToUpper(hash)
+	return c.CheckPwnedHashContext(context.Background(), hash, mode)
+}
+
+// CheckPwnedHashContext is like CheckPwnedHash but uses ctx for the request.
+// If ctx is canceled or its deadline passes, the request is aborted and the
+// context's error is returned.
+func (c *PwnedClient) CheckPwnedHashContext(ctx context.Context, hash, mode string) (int, error) {
+	result, err := c.CheckPwnedHashWithResultContext(ctx, hash, mode)
+	return result.Count, err
+}
+
+// CheckPwnedHashWithResult is like CheckPwnedHash but returns a Result with
+// the prefix sent to the API and the matched suffix along with the count.
+func (c *PwnedClient) CheckPwnedHashWithResult(hash, mode string) (Result, error) {
+	return c.CheckPwnedHashWithResultContext(context.Background(), hash, mode)
+}
 
-	reqURL, err := buildURL(c.baseURL, hash, mode)
+// CheckPwnedHashWithResultContext is like CheckPwnedHashWithResult but uses
+// ctx for the request.
+func (c *PwnedClient) CheckPwnedHashWithResultContext(ctx context.Context, hash, mode string) (Result, error) {
+	hash, err := CanonicalizeHash(hash, mode)
 	if err != nil {
-		return 0, err
+		return Result{}, err
+	}
+
+	if result, ok := c.localResult(hash, mode); ok {
+		return result, nil
 	}
 
-	req, err := newGetRequestWithPadding(reqURL)
+	body, contentType, mirror, err := c.openRange(ctx, hash[:c.prefixLen()], mode)
 	if err != nil {
-		return 0, err
+		return Result{}, err
 	}
+	defer body.Close()
+
+	result, err := processResponse(body, contentType, hash, c.prefixLen())
+	result.Mirror = mirror
+	return result, err
+}
 
-	resp, err := c.httpClient.Do(req)
+// MatchedLine returns the line of the range response that matched hash,
+// verbatim as "SUFFIX:count", e.g., to log or verify how a count was
+// derived. It is empty if no line matched, or if the hash was decided
+// without a request by the blocklist, allowlist, or Bloom filter. See
+// Result.Line.
+func (c *PwnedClient) MatchedLine(hash, mode string) (string, error) {
+	return c.MatchedLineContext(context.Background(), hash, mode)
+}
+
+// MatchedLineContext is like MatchedLine but uses ctx for the request.
+func (c *PwnedClient) MatchedLineContext(ctx context.Context, hash, mode string) (string, error) {
+	result, err := c.CheckPwnedHashWithResultContext(ctx, hash, mode)
 	if err != nil {
-		return 0, err
+		return "", err
+	}
+	return result.Line, nil
+}
+
+// localResult returns the result for a valid hash of type mode if it can be
+// decided without a request by the blocklist, allowlist, or Bloom filter.
+func (c *PwnedClient) localResult(hash, mode string) (Result, bool) {
+	prefix := hash[:c.prefixLen()]
+	if c.blocklist != nil && c.blocklist.Contains(hash, mode) {
+		return Result{Hash: hash, Prefix: prefix, Count: BlockedCount, Local: true, Blocked: true}, true
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return 0, fmt.Errorf("received non-OK HTTP status for %q: %d", reqURL, resp.StatusCode)
+	if c.allowlist != nil && c.allowlist.Contains(hash, mode) {
+		return Result{Hash: hash, Prefix: prefix, Local: true}, true
 	}
 
-	return processResponse(resp.Body, hash)
+	if c.bloom != nil && c.bloom.mode == mode && !c.bloom.MayContain(hash) {
+		return Result{Hash: hash, Prefix: prefix, Local: true}, true
+	}
+
+	return Result{}, false
 }
 
-// CheckPwnedPassword checks if the password has been exposed in breaches.
-// Mode is used to select which type of hash to use, i.e., ntlm or sha1.
-func (c *PwnedClient) CheckPwnedPassword(password, mode string) (int, error) {
-	var hash string
+// passwordHash returns the hash of type mode of the normalized password.
+func (c *PwnedClient) passwordHash(password, mode string) (string, error) {
+	password = c.normalize(password)
+
 	switch mode {
 	case "ntlm":
-		hash = ntHash(password)
+		return ntHash(password), nil
+	case "sha1":
+		return sha1Hash(password), nil
 	default:
-		hash = sha1Hash(password)
+		return "", fmt.Errorf("%w: %q", ErrInvalidMode, mode)
 	}
-	return c.CheckPwnedHash(hash, mode)
 }
 
-// CheckPwned checks if a password or hash has been exposed in breaches.
-func (c *PwnedClient) CheckPwned(text, lookup, mode string) (int, error) {
+// CheckPwnedPassword checks if the password has been exposed in breaches.
+// Mode is used to select which type of hash to use, i.e., ntlm or sha1.
+// Any other mode is an error.
+func (c *PwnedClient) CheckPwnedPassword(password, mode string) (int, error) {
+	return c.CheckPwnedPasswordContext(context.Background(), password, mode)
+}
+
+// CheckPwnedPasswordContext is like CheckPwnedPassword but uses ctx for the
+// request.
+func (c *PwnedClient) CheckPwnedPasswordContext(ctx context.Context, password, mode string) (int, error) {
+	hash, err := c.passwordHash(password, mode)
+	if err != nil {
+		return 0, err
+	}
+	return c.CheckPwnedHashContext(ctx, hash, mode)
+}
+
+// validateLookup checks that lookup and mode are valid and consistent with
+// text before any request is made. For hash lookups, the hash must be valid
+// for mode, so a hash of another mode reports ErrModeMismatch.
+func validateLookup(text, lookup, mode string) error {
+	if _, ok := hashLengths[mode]; !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+
 	switch lookup {
 	case "hash":
-		return c.CheckPwnedHash(text, mode)
+		_, err := CanonicalizeHash(text, mode)
+		return err
 	case "password":
-		return c.CheckPwnedPassword(text, mode)
+		return nil
 	default:
-		return 0, fmt.Errorf("invalid lookup type: %s", lookup)
+		return fmt.Errorf("invalid lookup type: %s", lookup)
 	}
 }
 
 // CheckPwned checks if a password or hash has been exposed in breaches.
-func CheckPwned(text, lookup, mode string) (int, error) {
+// The lookup and mode are validated against each other and the text, so a
+// hash of one type looked up with the mode of another is an error rather
+// than a silent query for the wrong range.
+func (c *PwnedClient) CheckPwned(text, lookup, mode string) (int, error) {
+	return c.CheckPwnedContext(context.Background(), text, lookup, mode)
+}
+
+// CheckPwnedContext is like CheckPwned but uses ctx for the request.
+func (c *PwnedClient) CheckPwnedContext(ctx context.Context, text, lookup, mode string) (int, error) {
+	result, err := c.CheckPwnedWithResultContext(ctx, text, lookup, mode)
+	return result.Count, err
+}
+
+// CheckPwnedWithResult is like CheckPwned but returns the full Result,
+// including the prefix sent to the API.
+func (c *PwnedClient) CheckPwnedWithResult(text, lookup, mode string) (Result, error) {
+	return c.CheckPwnedWithResultContext(context.Background(), text, lookup, mode)
+}
+
+// CheckPwnedWithResultContext is like CheckPwnedWithResult but uses ctx for
+// the request.
+func (c *PwnedClient) CheckPwnedWithResultContext(ctx context.Context, text, lookup, mode string) (Result, error) {
+	if err := validateLookup(text, lookup, mode); err != nil {
+		return Result{}, err
+	}
+
 	switch lookup {
 	case "hash":
-		return DefaultPwnedClient.CheckPwnedHash(text, mode)
+		return c.CheckPwnedHashWithResultContext(ctx, text, mode)
 	case "password":
-		return DefaultPwnedClient.CheckPwnedPassword(text, mode)
+		hash, err := c.passwordHash(text, mode)
+		if err != nil {
+			return Result{}, err
+		}
+		return c.CheckPwnedHashWithResultContext(ctx, hash, mode)
 	default:
-		return 0, fmt.Errorf("invalid lookup type: %s", lookup)
+		return Result{}, fmt.Errorf("invalid lookup type: %s", lookup)
+	}
+}
+
+// CheckPwned checks if a password or hash has been exposed in breaches using
+// the default client, see SetDefaultClient. The call is limited to the
+// client's HTTP timeout for each attempt allowed by its retry policy, or
+// DefaultCallTimeout if it has no timeout.
+func CheckPwned(text, lookup, mode string) (int, error) {
+	c, ctx, cancel := defaultCall()
+	defer cancel()
+	return c.CheckPwnedContext(ctx, text, lookup, mode)
+}
+
+// CheckPwnedContext is like CheckPwned but uses ctx for the request instead
+// of the default time limit.
+func CheckPwnedContext(ctx context.Context, text, lookup, mode string) (int, error) {
+	return defaultClient().CheckPwnedContext(ctx, text, lookup, mode)
+}
+
+// modesFor returns the modes to check for text. For hash lookups, only the
+// modes whose hash length matches the hash are returned.
+func modesFor(text, lookup string) ([]string, error) {
+	if lookup != "hash" {
+		return ValidHashes, nil
+	}
+
+	text = stripSpace(text)
+	var modes []string
+	for _, mode := range ValidHashes {
+		if len(text) == hashLengths[mode] {
+			modes = append(modes, mode)
+		}
+	}
+	if len(modes) == 0 {
+		return nil, fmt.Errorf("%w: length %d does not match any mode", ErrInvalidHash, len(text))
+	}
+	return modes, nil
+}
+
+// ModeCount is the count for a password or hash under one mode.
+type ModeCount struct {
+	Mode  string
+	Count int
+}
+
+// CheckPwnedAllModes checks if a password or hash has been exposed in
+// breaches under every mode in ValidHashes. For hash lookups, only the modes
+// whose hash length matches the hash are checked. The lookups are performed
+// concurrently, but the counts are returned in ValidHashes order, so the
+// output is deterministic. If any lookup fails, the counts for the
+// successful modes are returned, still in order, along with the joined
+// errors.
+func (c *PwnedClient) CheckPwnedAllModes(text, lookup string) ([]ModeCount, error) {
+	return c.checkPwnedAllModes(context.Background(), text, lookup)
+}
+
+// checkPwnedAllModes is CheckPwnedAllModes with ctx for the requests.
+func (c *PwnedClient) checkPwnedAllModes(ctx context.Context, text, lookup string) ([]ModeCount, error) {
+	modes, err := modesFor(text, lookup)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int, len(modes))
+	errs := make([]error, len(modes))
+	var wg sync.WaitGroup
+	for i, mode := range modes {
+		wg.Add(1)
+		go func(i int, mode string) {
+			defer wg.Done()
+			counts[i], errs[i] = c.CheckPwnedContext(ctx, text, lookup, mode)
+			if errs[i] != nil {
+				errs[i] = fmt.Errorf("%s: %w", mode, errs[i])
+			}
+		}(i, mode)
+	}
+	wg.Wait()
+
+	results := make([]ModeCount, 0, len(modes))
+	for i, mode := range modes {
+		if errs[i] == nil {
+			results = append(results, ModeCount{Mode: mode, Count: counts[i]})
+		}
 	}
+	return results, errors.Join(errs...)
+}
+
+// CheckPwnedAllModes checks if a password or hash has been exposed in
+// breaches under every mode in ValidHashes using the default client. The
+// call is limited like CheckPwned.
+func CheckPwnedAllModes(text, lookup string) ([]ModeCount, error) {
+	c, ctx, cancel := defaultCall()
+	defer cancel()
+	return c.checkPwnedAllModes(ctx, text, lookup)
+}
+
+// AnyModePwned reports whether a password or hash has been exposed in
+// breaches under any mode in ValidHashes, returning the first mode found.
+// For hash lookups, only the modes whose hash length matches the hash are
+// checked. The lookups are performed concurrently and the remaining lookups
+// are canceled as soon as one finds the text. Errors are returned only if no
+// mode found the text.
+func (c *PwnedClient) AnyModePwned(ctx context.Context, text, lookup string) (bool, string, error) {
+	modes, err := modesFor(text, lookup)
+	if err != nil {
+		return false, "", err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type modeResult struct {
+		mode  string
+		count int
+		err   error
+	}
+	results := make(chan modeResult, len(modes))
+	for _, mode := range modes {
+		go func(mode string) {
+			count, err := c.CheckPwnedContext(ctx, text, lookup, mode)
+			results <- modeResult{mode: mode, count: count, err: err}
+		}(mode)
+	}
+
+	var errs []error
+	for range modes {
+		r := <-results
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.mode, r.err))
+			continue
+		}
+		if r.count > 0 {
+			return true, r.mode, nil
+		}
+	}
+	return false, "", errors.Join(errs...)
+}
+
+// AnyModePwned reports whether a password or hash has been exposed in
+// breaches under any mode in ValidHashes using the default client. If ctx
+// has no deadline, the call is limited like CheckPwned.
+func AnyModePwned(ctx context.Context, text, lookup string) (bool, string, error) {
+	c, ctx, cancel := defaultCallContext(ctx)
+	defer cancel()
+	return c.AnyModePwned(ctx, text, lookup)
 }
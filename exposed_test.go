@@ -3,11 +3,20 @@
 package exposed_test
 
 import (
+	"context"
+	"errors"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/bnixon67/exposed"
 )
@@ -122,3 +131,1077 @@ func TestCheckPwnedPassword(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckPwnedAllModes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("mode") {
+		case "ntlm":
+			_, _ = w.Write([]byte(readFile("testdata/8846F")))
+		default:
+			_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+		}
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+	counts, err := c.CheckPwnedAllModes("password", "password")
+	if err != nil {
+		t.Fatalf("CheckPwnedAllModes() error = %v", err)
+	}
+
+	// ordered as in ValidHashes
+	want := []exposed.ModeCount{{Mode: "sha1", Count: 10434004}, {Mode: "ntlm", Count: 10434004}}
+	if len(counts) != len(want) {
+		t.Fatalf("CheckPwnedAllModes() = %v, expected %v", counts, want)
+	}
+	for i := range want {
+		if counts[i] != want[i] {
+			t.Errorf("CheckPwnedAllModes()[%d] = %v, expected %v", i, counts[i], want[i])
+		}
+	}
+}
+
+func TestAnyModePwned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("mode") {
+		case "ntlm":
+			_, _ = w.Write([]byte(readFile("testdata/8846F")))
+		default:
+			// block until the hit under ntlm cancels this request
+			<-r.Context().Done()
+		}
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+	found, mode, err := c.AnyModePwned(context.Background(), "password", "password")
+	if err != nil {
+		t.Fatalf("AnyModePwned() error = %v", err)
+	}
+	if !found || mode != "ntlm" {
+		t.Errorf("AnyModePwned() = %v, %q, expected true, %q", found, mode, "ntlm")
+	}
+
+	found, _, err = c.AnyModePwned(context.Background(), "8846F7EAEE8FB117AD06BDD830B7586D", "hash")
+	if err != nil || found {
+		t.Errorf("AnyModePwned(unknown hash) = %v, %v, expected false, nil", found, err)
+	}
+}
+
+func TestWithMethod(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       []exposed.Option
+		wantMethod string
+		wantErr    bool
+	}{
+		{
+			name:       "default",
+			wantMethod: http.MethodGet,
+		},
+		{
+			name:       "POST",
+			opts:       []exposed.Option{exposed.WithMethod(http.MethodPost)},
+			wantMethod: http.MethodPost,
+		},
+		{
+			// a HEAD response has no body, so every hash would be not found
+			name:    "HEAD",
+			opts:    []exposed.Option{exposed.WithMethod(http.MethodHead)},
+			wantErr: true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotMethod, gotPadding string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotMethod = r.Method
+				gotPadding = r.Header.Get("Add-Padding")
+				_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL, tc.opts...)
+			count, err := c.CheckPwnedPassword("password", "sha1")
+			if tc.wantErr {
+				if err == nil || gotMethod != "" {
+					t.Errorf("CheckPwnedPassword() = %d, %v, method %q, expected error without a request", count, err, gotMethod)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CheckPwnedPassword() error = %v", err)
+			}
+			if count != 10434004 {
+				t.Errorf("count = %d, expected 10434004", count)
+			}
+
+			if gotMethod != tc.wantMethod {
+				t.Errorf("method = %q, expected %q", gotMethod, tc.wantMethod)
+			}
+			if gotPadding != "true" {
+				t.Errorf("Add-Padding = %q, expected %q", gotPadding, "true")
+			}
+		})
+	}
+}
+
+func TestWithPaddingHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []exposed.Option
+		wantName  string
+		wantValue string
+		wantErr   error
+	}{
+		{name: "default", wantName: "Add-Padding", wantValue: "true"},
+		{
+			name:      "custom",
+			opts:      []exposed.Option{exposed.WithPaddingHeader("x-pad-response", "1")},
+			wantName:  "X-Pad-Response",
+			wantValue: "1",
+		},
+		{
+			name:    "invalid name",
+			opts:    []exposed.Option{exposed.WithPaddingHeader("Add Padding", "true")},
+			wantErr: exposed.ErrInvalidHeader,
+		},
+		{
+			name:    "empty value",
+			opts:    []exposed.Option{exposed.WithPaddingHeader("Add-Padding", "")},
+			wantErr: exposed.ErrInvalidHeader,
+		},
+		{
+			name:    "control character in value",
+			opts:    []exposed.Option{exposed.WithPaddingHeader("Add-Padding", "true\r\nX-Injected: 1")},
+			wantErr: exposed.ErrInvalidHeader,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL, tc.opts...)
+			_, err := c.CheckPwnedPassword("password", "sha1")
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("CheckPwnedPassword() error = %v, expected %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				return
+			}
+
+			if got := header.Get(tc.wantName); got != tc.wantValue {
+				t.Errorf("%s = %q, expected %q", tc.wantName, got, tc.wantValue)
+			}
+			if tc.wantName != "Add-Padding" && header.Get("Add-Padding") != "" {
+				t.Errorf("Add-Padding sent with custom padding header")
+			}
+		})
+	}
+}
+
+func TestWithAccept(t *testing.T) {
+	tests := []struct {
+		name         string
+		contentType  string
+		responseBody string
+		wantCount    int
+		wantErr      bool
+	}{
+		{
+			name:         "JSON found",
+			contentType:  "application/json; charset=utf-8",
+			responseBody: `{"1E4C9B93F3F0682250B6CF8331B7EE68FD8":10434004,"003CD215739D7C1B2218670D26F81408237":1}`,
+			wantCount:    10434004,
+		},
+		{
+			name:         "JSON lowercase suffix",
+			contentType:  "application/json",
+			responseBody: `{"1e4c9b93f3f0682250b6cf8331b7ee68fd8":3}`,
+			wantCount:    3,
+		},
+		{
+			name:         "JSON not found",
+			contentType:  "application/json",
+			responseBody: `{"003CD215739D7C1B2218670D26F81408237":1}`,
+			wantCount:    0,
+		},
+		{
+			name:         "invalid JSON",
+			contentType:  "application/json",
+			responseBody: `not json`,
+			wantErr:      true,
+		},
+		{
+			name:         "text fallback",
+			contentType:  "text/plain",
+			responseBody: readFile("testdata/5BAA6"),
+			wantCount:    10434004,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotAccept string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAccept = r.Header.Get("Accept")
+				w.Header().Set("Content-Type", tc.contentType)
+				_, _ = w.Write([]byte(tc.responseBody))
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithAccept("application/json"))
+			count, err := c.CheckPwnedPassword("password", "sha1")
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CheckPwnedPassword() error = %v, expectedErr %v", err, tc.wantErr)
+			}
+			if count != tc.wantCount {
+				t.Errorf("CheckPwnedPassword() = %v, expected %v", count, tc.wantCount)
+			}
+			if gotAccept != "application/json" {
+				t.Errorf("Accept = %q, expected %q", gotAccept, "application/json")
+			}
+		})
+	}
+}
+
+func TestTruncatedResponse(t *testing.T) {
+	body := readFile("testdata/8846F")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// claim the full body but send only part of it before closing
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(body[:100]))
+
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Errorf("Hijack() error = %v", err)
+			return
+		}
+		conn.Close()
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+	_, err := c.CheckPwnedPassword("password", "ntlm")
+	if !errors.Is(err, exposed.ErrTruncatedResponse) {
+		t.Errorf("CheckPwnedPassword() error = %v, expected %v", err, exposed.ErrTruncatedResponse)
+	}
+}
+
+func TestCheckPwnedHashWithResult(t *testing.T) {
+	tests := []struct {
+		name         string
+		hash         string
+		mode         string
+		responseBody string
+		want         exposed.Result
+		wantErr      bool
+	}{
+		{
+			name:         "SHA-1 found",
+			hash:         "5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8",
+			mode:         "sha1",
+			responseBody: readFile("testdata/5BAA6"),
+			want: exposed.Result{
+				Hash:   "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+				Prefix: "5BAA6",
+				Suffix: "1E4C9B93F3F0682250B6CF8331B7EE68FD8",
+				Count:  10434004,
+				Line:   "1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004",
+			},
+		},
+		{
+			name:         "padding entry",
+			hash:         "5BAA6FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+			mode:         "sha1",
+			responseBody: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:0\n",
+			want: exposed.Result{
+				Hash:   "5BAA6FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+				Prefix: "5BAA6",
+				Suffix: "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF",
+				Count:  0,
+				Line:   "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:0",
+			},
+		},
+		{
+			name:         "NTLM not found",
+			hash:         "8846F7EAEE8FB117AD06BDD830B7586D",
+			mode:         "ntlm",
+			responseBody: readFile("testdata/8846F"),
+			want:         exposed.Result{Hash: "8846F7EAEE8FB117AD06BDD830B7586D", Prefix: "8846F"},
+		},
+		{
+			name:         "count not found",
+			hash:         "8846F7EAEE8FB117AD06BDD830B7586C",
+			mode:         "ntlm",
+			responseBody: readFile("testdata/8846F.bad"),
+			wantErr:      true,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(tc.responseBody))
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+			got, err := c.CheckPwnedHashWithResult(tc.hash, tc.mode)
+
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("CheckPwnedHashWithResult() error = %v, expectedErr %v", err, tc.wantErr)
+			}
+			if tc.wantErr {
+				return
+			}
+			tc.want.Mirror = server.URL
+			if got != tc.want {
+				t.Errorf("CheckPwnedHashWithResult() = %+v, expected %+v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResultFound(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6") + "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF:0\r\n"))
+	}))
+	defer server.Close()
+
+	blocklist := exposed.NewHashList()
+	blocklist.AddPassword("blocked")
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithBlocklist(blocklist))
+	tests := []struct {
+		name, text, lookup string
+		want               bool
+	}{
+		{"found", "password", "password", true},
+		{"padding entry", "5BAA6FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFF", "hash", false},
+		{"not found", "5BAA60000000000000000000000000000000000F", "hash", false},
+		{"blocked", "blocked", "password", true},
+	}
+	for _, tc := range tests {
+		result, err := c.CheckPwnedWithResult(tc.text, tc.lookup, "sha1")
+		if err != nil {
+			t.Fatalf("%s: CheckPwnedWithResult() error = %v", tc.name, err)
+		}
+		if got := result.Found(); got != tc.want {
+			t.Errorf("%s: Found() = %v, expected %v for %+v", tc.name, got, tc.want, result)
+		}
+	}
+}
+
+func TestCheckPwnedWithResult(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	want := exposed.Result{
+		Hash:   "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+		Prefix: "5BAA6",
+		Suffix: "1E4C9B93F3F0682250B6CF8331B7EE68FD8",
+		Count:  10434004,
+		Line:   "1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004",
+		Mirror: server.URL,
+	}
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+	for _, tc := range []struct{ text, lookup string }{
+		{"password", "password"},
+		{"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", "hash"},
+	} {
+		got, err := c.CheckPwnedWithResult(tc.text, tc.lookup, "sha1")
+		if err != nil {
+			t.Fatalf("CheckPwnedWithResult(%q) error = %v", tc.lookup, err)
+		}
+		if got != want {
+			t.Errorf("CheckPwnedWithResult(%q) = %+v, expected %+v", tc.lookup, got, want)
+		}
+	}
+}
+
+func TestMatchedLine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+	tests := []struct {
+		hash    string
+		want    string
+		wantErr bool
+	}{
+		{"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004", false},
+		{"5BAA60000000000000000000000000000000000F", "", false},
+		{"5BAA6", "", true},
+	}
+	for _, tc := range tests {
+		got, err := c.MatchedLine(tc.hash, "sha1")
+		if (err != nil) != tc.wantErr {
+			t.Errorf("MatchedLine(%q) error = %v, expectedErr %v", tc.hash, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("MatchedLine(%q) = %q, expected %q", tc.hash, got, tc.want)
+		}
+	}
+}
+
+func TestCheckPwnedHashModes(t *testing.T) {
+	const (
+		sha1Hash = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+		ntlmHash = "8846F7EAEE8FB117AD06BDD830B7586C"
+	)
+
+	tests := []struct {
+		name      string
+		hash      string
+		mode      string
+		wantQuery string
+		wantErr   error
+	}{
+		{name: "SHA-1 hash with sha1 mode", hash: sha1Hash, mode: "sha1", wantQuery: ""},
+		{name: "NTLM hash with ntlm mode", hash: ntlmHash, mode: "ntlm", wantQuery: "mode=ntlm"},
+		{name: "NTLM hash with sha1 mode", hash: ntlmHash, mode: "sha1", wantErr: exposed.ErrInvalidHash},
+		{name: "SHA-1 hash with ntlm mode", hash: sha1Hash, mode: "ntlm", wantErr: exposed.ErrInvalidHash},
+		{name: "non-hex hash", hash: "ZBAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", mode: "sha1", wantErr: exposed.ErrInvalidHash},
+		{name: "short hash", hash: "5BAA6", mode: "sha1", wantErr: exposed.ErrInvalidHash},
+		{name: "invalid mode", hash: sha1Hash, mode: "md5", wantErr: exposed.ErrInvalidMode},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			var gotQuery string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				gotQuery = r.URL.RawQuery
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+			_, err := c.CheckPwnedHash(tc.hash, tc.mode)
+
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("CheckPwnedHash() error = %v, expected %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil {
+				if requests != 0 {
+					t.Errorf("requests = %d, expected none for invalid input", requests)
+				}
+				return
+			}
+			if gotQuery != tc.wantQuery {
+				t.Errorf("query = %q, expected %q", gotQuery, tc.wantQuery)
+			}
+		})
+	}
+}
+
+func TestCheckPwnedPasswordInvalidMode(t *testing.T) {
+	c := exposed.NewPwnedClient(&http.Client{}, "http://127.0.0.1:0")
+	_, err := c.CheckPwnedPassword("password", "md5")
+	if !errors.Is(err, exposed.ErrInvalidMode) {
+		t.Errorf("CheckPwnedPassword() error = %v, expected %v", err, exposed.ErrInvalidMode)
+	}
+}
+
+func TestWithUserAgent(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []exposed.Option
+		want string
+	}{
+		{name: "default", want: exposed.DefaultUserAgent},
+		{name: "empty", opts: []exposed.Option{exposed.WithUserAgent("")}, want: exposed.DefaultUserAgent},
+		{name: "custom", opts: []exposed.Option{exposed.WithUserAgent("scanner/1.0")}, want: "scanner/1.0"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("User-Agent")
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL, tc.opts...)
+			if _, err := c.CheckPwnedPassword("password", "sha1"); err != nil {
+				t.Fatalf("CheckPwnedPassword() error = %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("User-Agent = %q, expected %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWithCache(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithClock(clock),
+		exposed.WithCache(1, time.Minute))
+
+	check := func(password string, wantRequests int) {
+		t.Helper()
+		if _, err := c.CheckPwnedPassword(password, "sha1"); err != nil {
+			t.Fatalf("CheckPwnedPassword() error = %v", err)
+		}
+		if requests != wantRequests {
+			t.Errorf("requests = %d, expected %d", requests, wantRequests)
+		}
+	}
+
+	check("password", 1)
+	check("password", 1) // cached
+
+	clock.now = clock.now.Add(time.Minute)
+	check("password", 2) // expired
+
+	check("different prefix", 3)
+	check("password", 4) // evicted
+
+	want := exposed.CacheStats{Hits: 1, Misses: 4, Evictions: 2, Size: 1, Capacity: 1}
+	if got := c.CacheStats(); got != want {
+		t.Errorf("CacheStats() = %+v, expected %+v", got, want)
+	}
+}
+
+func TestCacheStatsConcurrent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithCache(10, 0))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = c.CheckPwnedPassword("password", "sha1")
+			_ = c.CacheStats()
+		}()
+	}
+	wg.Wait()
+
+	stats := c.CacheStats()
+	if stats.Hits+stats.Misses != 10 || stats.Size != 1 {
+		t.Errorf("CacheStats() = %+v, expected 10 lookups and size 1", stats)
+	}
+	if disabled := exposed.NewPwnedClient(nil, server.URL).CacheStats(); disabled != (exposed.CacheStats{}) {
+		t.Errorf("CacheStats() without cache = %+v, expected zero", disabled)
+	}
+}
+
+func TestDownloadRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/8846F" || r.URL.Query().Get("mode") != "ntlm" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(readFile("testdata/8846F")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+	var buf strings.Builder
+	if err := c.DownloadRange(context.Background(), "8846f", "ntlm", &buf); err != nil {
+		t.Fatalf("DownloadRange() error = %v", err)
+	}
+	if buf.String() != readFile("testdata/8846F") {
+		t.Error("DownloadRange() body differs from the range")
+	}
+
+	if err := c.DownloadRange(context.Background(), "8846", "ntlm", &buf); !errors.Is(err, exposed.ErrInvalidHash) {
+		t.Errorf("DownloadRange(short prefix) error = %v, expected %v", err, exposed.ErrInvalidHash)
+	}
+	if err := c.DownloadRange(context.Background(), "5BAA6", "sha1", &buf); err == nil {
+		t.Error("DownloadRange(missing) error = nil, expected error")
+	}
+}
+
+func TestWithNotFoundAsEmpty(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	strict := exposed.NewPwnedClient(&http.Client{}, server.URL)
+	if _, err := strict.CheckPwnedPassword("password", "sha1"); err == nil {
+		t.Error("CheckPwnedPassword() error = nil with 404, expected error by default")
+	}
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithNotFoundAsEmpty(true), exposed.WithCache(10, 0))
+	for i := 0; i < 2; i++ {
+		count, err := c.CheckPwnedPassword("password", "sha1")
+		if err != nil {
+			t.Fatalf("CheckPwnedPassword() error = %v", err)
+		}
+		if count != 0 {
+			t.Errorf("CheckPwnedPassword() = %d, expected 0", count)
+		}
+	}
+
+	// one request by the strict client and one cached by the other
+	if requests != 2 {
+		t.Errorf("got %d requests, expected 2", requests)
+	}
+}
+
+func TestFetchRange(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/5BAA6" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+	body, err := c.FetchRange(context.Background(), "5baa6", "sha1")
+	if err != nil {
+		t.Fatalf("FetchRange() error = %v", err)
+	}
+	b, err := io.ReadAll(body)
+	body.Close()
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(b) != readFile("testdata/5BAA6") {
+		t.Error("FetchRange() body differs from the range")
+	}
+
+	if _, err := c.FetchRange(context.Background(), "5BAA6", "md5"); !errors.Is(err, exposed.ErrInvalidMode) {
+		t.Errorf("FetchRange(invalid mode) error = %v, expected %v", err, exposed.ErrInvalidMode)
+	}
+	if _, err := c.FetchRange(context.Background(), "00000", "sha1"); err == nil {
+		t.Error("FetchRange(missing) error = nil, expected error")
+	}
+}
+
+func TestFetchRanges(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/5BAA6":
+			_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+		case "/8846F":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"7eaee8fb117ad06bdd830b7586c": 9, "00000000000000000000000000A": 0}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+	ranges, err := c.FetchRanges(context.Background(), []string{"5baa6", "8846F", "5BAA6"}, "sha1")
+	if err != nil {
+		t.Fatalf("FetchRanges() error = %v", err)
+	}
+	if got := requests.Load(); got != 2 {
+		t.Errorf("got %d requests, expected 2", got)
+	}
+	if got := ranges["5BAA6"]["1E4C9B93F3F0682250B6CF8331B7EE68FD8"]; got != 10434004 {
+		t.Errorf("ranges[5BAA6] count = %d, expected %d", got, 10434004)
+	}
+	if want := map[string]int{"7EAEE8FB117AD06BDD830B7586C": 9}; len(ranges["8846F"]) != 1 || ranges["8846F"]["7EAEE8FB117AD06BDD830B7586C"] != 9 {
+		t.Errorf("ranges[8846F] = %v, expected %v", ranges["8846F"], want)
+	}
+
+	ranges, err = c.FetchRanges(context.Background(), []string{"5BAA6", "00000"}, "sha1")
+	if err == nil || !strings.Contains(err.Error(), "00000") {
+		t.Errorf("FetchRanges(missing) error = %v, expected error for 00000", err)
+	}
+	if _, ok := ranges["5BAA6"]; !ok || len(ranges) != 1 {
+		t.Errorf("FetchRanges(missing) = %d ranges, expected only 5BAA6", len(ranges))
+	}
+
+	if _, err := c.FetchRanges(context.Background(), []string{"5BAA"}, "sha1"); !errors.Is(err, exposed.ErrInvalidHash) {
+		t.Errorf("FetchRanges(invalid prefix) error = %v, expected %v", err, exposed.ErrInvalidHash)
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithRateLimit(20, 1))
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := c.CheckPwnedPassword("password", "sha1"); err != nil {
+			t.Fatalf("CheckPwnedPassword() error = %v", err)
+		}
+	}
+
+	// the first request uses the burst, the next two wait 50ms each
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("3 requests at 20 rps took %v, expected at least 100ms", elapsed)
+	}
+	if wait := c.Stats().LimiterWait; wait < 90*time.Millisecond {
+		t.Errorf("Stats().LimiterWait = %v, expected at least 100ms", wait)
+	}
+}
+
+func TestCheckPwnedConflicts(t *testing.T) {
+	const (
+		sha1Hash = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+		ntlmHash = "8846F7EAEE8FB117AD06BDD830B7586C"
+	)
+
+	tests := []struct {
+		name    string
+		text    string
+		lookup  string
+		mode    string
+		wantErr error
+	}{
+		{name: "SHA-1 hash with ntlm mode", text: sha1Hash, lookup: "hash", mode: "ntlm", wantErr: exposed.ErrModeMismatch},
+		{name: "NTLM hash with sha1 mode", text: ntlmHash, lookup: "hash", mode: "sha1", wantErr: exposed.ErrModeMismatch},
+		{name: "lowercase NTLM hash with sha1 mode", text: strings.ToLower(ntlmHash), lookup: "hash", mode: "sha1", wantErr: exposed.ErrModeMismatch},
+		{name: "unknown length hash", text: "5BAA61E4", lookup: "hash", mode: "sha1", wantErr: exposed.ErrInvalidHash},
+		{name: "password lookup with invalid mode", text: "password", lookup: "password", mode: "md5", wantErr: exposed.ErrInvalidMode},
+		{name: "hash lookup with invalid mode", text: sha1Hash, lookup: "hash", mode: "md5", wantErr: exposed.ErrInvalidMode},
+		{name: "consistent SHA-1", text: sha1Hash, lookup: "hash", mode: "sha1"},
+		{name: "consistent NTLM", text: ntlmHash, lookup: "hash", mode: "ntlm"},
+		{name: "password that looks like a hash", text: sha1Hash, lookup: "password", mode: "ntlm"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+			_, err := c.CheckPwned(tc.text, tc.lookup, tc.mode)
+
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("CheckPwned() error = %v, expected %v", err, tc.wantErr)
+			}
+			if tc.wantErr != nil && requests != 0 {
+				t.Errorf("requests = %d, expected none for conflicting input", requests)
+			}
+		})
+	}
+}
+
+func TestWithResponseSizeLimit(t *testing.T) {
+	// a body of padding lines that never matches, then the real suffix
+	oversized := strings.Repeat("00000000000000000000000000000000000:0\n", 1000) +
+		"1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\n"
+
+	tests := []struct {
+		name          string
+		limit         int64
+		chunked       bool
+		wantCount     int
+		wantErr       error
+		responseBytes string
+	}{
+		{name: "under limit", limit: 1 << 20, responseBytes: oversized, wantCount: 10434004},
+		{name: "over limit", limit: 1024, responseBytes: oversized, wantErr: exposed.ErrResponseTooLarge},
+		{name: "over limit chunked", limit: 1024, chunked: true, responseBytes: oversized, wantErr: exposed.ErrResponseTooLarge},
+		{name: "default limit", responseBytes: strings.Repeat("0", exposed.DefaultResponseSizeLimit+1), wantErr: exposed.ErrResponseTooLarge},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tc.chunked {
+					w.Header().Set("Content-Length", strconv.Itoa(len(tc.responseBytes)))
+				}
+				_, _ = w.Write([]byte(tc.responseBytes))
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithResponseSizeLimit(tc.limit))
+			count, err := c.CheckPwnedPassword("password", "sha1")
+
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("CheckPwnedPassword() error = %v, expected %v", err, tc.wantErr)
+			}
+			if count != tc.wantCount {
+				t.Errorf("CheckPwnedPassword() = %v, expected %v", count, tc.wantCount)
+			}
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{name: "ok", status: http.StatusOK},
+		{name: "unavailable", status: http.StatusServiceUnavailable, wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotPath, gotKey string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotKey = r.Header.Get("hibp-api-key")
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithAPIKey("key"))
+			err := c.Ping(context.Background())
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("Ping() error = %v, expectedErr %v", err, tc.wantErr)
+			}
+			if gotPath != "/00000" || gotKey != "key" {
+				t.Errorf("request path %q, key %q, expected %q, %q", gotPath, gotKey, "/00000", "key")
+			}
+		})
+	}
+}
+
+func TestWithRequestBudget(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithRequestBudget(2), exposed.WithCache(10, 0))
+
+	for _, password := range []string{"password", "other", "password"} {
+		if _, err := c.CheckPwnedPassword(password, "sha1"); err != nil {
+			t.Fatalf("CheckPwnedPassword(%q) error = %v", password, err)
+		}
+	}
+
+	// a new prefix needs a request, which is over budget
+	_, err := c.CheckPwnedPassword("another", "sha1")
+	if !errors.Is(err, exposed.ErrBudgetExceeded) {
+		t.Errorf("CheckPwnedPassword() error = %v, expected %v", err, exposed.ErrBudgetExceeded)
+	}
+	if requests != 2 {
+		t.Errorf("got %d requests, expected 2", requests)
+	}
+}
+
+func TestWithUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "range.sock")
+	l, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets not supported: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/range/5BAA6" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	server.Listener = l
+	server.Start()
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(nil, "http://localhost/range", exposed.WithUnixSocket(socket))
+
+	count, err := c.CheckPwnedPassword("password", "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedPassword() error = %v", err)
+	}
+	if count != 10434004 {
+		t.Errorf("CheckPwnedPassword() = %v, expected %v", count, 10434004)
+	}
+}
+
+func TestCanonicalizeHash(t *testing.T) {
+	const sha1Hash = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+	tests := []struct {
+		name    string
+		hash    string
+		mode    string
+		want    string
+		wantErr error
+	}{
+		{name: "canonical", hash: sha1Hash, mode: "sha1", want: sha1Hash},
+		{name: "lowercase", hash: strings.ToLower(sha1Hash), mode: "sha1", want: sha1Hash},
+		{name: "surrounding whitespace", hash: "  " + sha1Hash + "\r\n", mode: "sha1", want: sha1Hash},
+		{name: "grouped with spaces", hash: "5baa6 1e4c9 b93f3 f0682 250b6 cf833 1b7ee 68fd8", mode: "sha1", want: sha1Hash},
+		{name: "wrapped lines", hash: "5BAA61E4C9B93F3F0682\n\t250B6CF8331B7EE68FD8", mode: "sha1", want: sha1Hash},
+		{name: "non-breaking space", hash: "8846F7EAEE8FB117\u00a0AD06BDD830B7586C", mode: "ntlm", want: "8846F7EAEE8FB117AD06BDD830B7586C"},
+		{name: "empty", hash: " \n ", mode: "sha1", wantErr: exposed.ErrInvalidHash},
+		{name: "non-hex", hash: "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FDZ", mode: "sha1", wantErr: exposed.ErrInvalidHash},
+		{name: "wrong length for mode", hash: sha1Hash, mode: "ntlm", wantErr: exposed.ErrModeMismatch},
+		{name: "invalid mode", hash: sha1Hash, mode: "md5", wantErr: exposed.ErrInvalidMode},
+	}
+
+	for _, tc := range tests {
+		got, err := exposed.CanonicalizeHash(tc.hash, tc.mode)
+		if !errors.Is(err, tc.wantErr) {
+			t.Errorf("%s: CanonicalizeHash() error = %v, expected %v", tc.name, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("%s: CanonicalizeHash() = %q, expected %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCheckPwnedHashMessyInput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+	count, err := c.CheckPwned(" 5baa6 1e4c9b93f3f0682250b6cf8331b7ee68fd8\n", "hash", "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwned() error = %v", err)
+	}
+	if count != 10434004 {
+		t.Errorf("CheckPwned() = %d, expected %d", count, 10434004)
+	}
+}
+
+func TestEmptyResponseBody(t *testing.T) {
+	const hash = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+
+	for _, contentType := range []string{"text/plain", "application/json"} {
+		for _, body := range []string{"", " ", "\n", "\r\n", " \t\r\n\n"} {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", contentType)
+				_, _ = w.Write([]byte(body))
+			}))
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+			result, err := c.CheckPwnedHashWithResult(hash, "sha1")
+			if err != nil || result.Count != 0 || result.Suffix != "" {
+				t.Errorf("%s %q: CheckPwnedHashWithResult() = %+v, %v, expected not found", contentType, body, result, err)
+			}
+
+			ranges, err := c.FetchRanges(context.Background(), []string{"5BAA6"}, "sha1")
+			if err != nil || len(ranges["5BAA6"]) != 0 {
+				t.Errorf("%s %q: FetchRanges() = %v, %v, expected an empty range", contentType, body, ranges, err)
+			}
+
+			server.Close()
+		}
+	}
+}
+
+func TestWithPrefixLength(t *testing.T) {
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_, _ = w.Write([]byte("E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\r\n"))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithPrefixLength(6))
+	result, err := c.CheckPwnedWithResult("password", "password", "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedWithResult() error = %v", err)
+	}
+	if gotPath != "/5BAA61" {
+		t.Errorf("path = %q, expected %q", gotPath, "/5BAA61")
+	}
+	if result.Prefix != "5BAA61" || result.Suffix != "E4C9B93F3F0682250B6CF8331B7EE68FD8" || result.Count != 10434004 {
+		t.Errorf("result = %+v, expected 6 character prefix with count 10434004", result)
+	}
+
+	for _, n := range []int{-1, 32, 40} {
+		c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithPrefixLength(n))
+		_, err := c.CheckPwnedPassword("password", "sha1")
+		// the shortest hash, ntlm, is always the one named
+		if err == nil || !strings.Contains(err.Error(), "between 1 and 31 for ntlm hashes") {
+			t.Errorf("WithPrefixLength(%d) error = %v, expected the ntlm bound", n, err)
+		}
+	}
+}
+
+func TestCheckPwnedLowercaseHashes(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		switch r.URL.Path {
+		case "/5BAA6":
+			_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+		case "/8846F":
+			_, _ = w.Write([]byte(readFile("testdata/8846F")))
+		}
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+	tests := []struct {
+		hash, mode, prefix string
+	}{
+		{"5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8", "sha1", "/5BAA6"},
+		{"5BAA61e4c9b93f3f0682250B6CF8331b7ee68fd8", "sha1", "/5BAA6"},
+		{"8846f7eaee8fb117ad06bdd830b7586c", "ntlm", "/8846F"},
+		{"8846F7eaee8fb117AD06bdd830b7586C", "ntlm", "/8846F"},
+	}
+	for _, tc := range tests {
+		paths = nil
+
+		count, err := c.CheckPwnedHash(tc.hash, tc.mode)
+		if err != nil || count != 10434004 {
+			t.Errorf("CheckPwnedHash(%q, %q) = %d, %v, expected 10434004, nil", tc.hash, tc.mode, count, err)
+		}
+
+		count, err = c.CheckPwned(tc.hash, "hash", tc.mode)
+		if err != nil || count != 10434004 {
+			t.Errorf("CheckPwned(%q, %q) = %d, %v, expected 10434004, nil", tc.hash, tc.mode, count, err)
+		}
+
+		counts, err := c.CheckPwnedAllModes(tc.hash, "hash")
+		if err != nil || len(counts) != 1 || counts[0] != (exposed.ModeCount{Mode: tc.mode, Count: 10434004}) {
+			t.Errorf("CheckPwnedAllModes(%q) = %v, %v, expected %s count 10434004", tc.hash, counts, err, tc.mode)
+		}
+
+		// the prefix is normalized before the request
+		for _, p := range paths {
+			if p != tc.prefix {
+				t.Errorf("%s: requested %q, expected %q", tc.hash, p, tc.prefix)
+			}
+		}
+	}
+}
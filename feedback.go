@@ -0,0 +1,58 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"strconv"
+	"strings"
+)
+
+// FeedbackLevel is a message for counts of at least MinCount. Any "{count}"
+// in Message is replaced with the count.
+type FeedbackLevel struct {
+	MinCount int
+	Message  string
+}
+
+// Feedback generates human-readable messages for breach counts, e.g., for
+// signup forms. Applications can customize or localize the messages by
+// providing their own levels and count format.
+type Feedback struct {
+	// Levels are the messages to use. The level with the highest MinCount
+	// that does not exceed the count is used.
+	Levels []FeedbackLevel
+
+	// FormatCount formats the count for "{count}". If nil, the count is
+	// formatted with strconv.Itoa.
+	FormatCount func(count int) string
+}
+
+// DefaultFeedback provides English messages for breach counts.
+var DefaultFeedback = Feedback{
+	Levels: []FeedbackLevel{
+		{MinCount: 0, Message: "This password has not appeared in any known breaches."},
+		{MinCount: 1, Message: "This password has appeared in {count} breaches; choose another."},
+		{MinCount: 1000, Message: "This password is very common and has appeared in {count} breaches; choose another."},
+	},
+}
+
+// Message returns the message for count, or an empty string if no level
+// applies.
+func (f Feedback) Message(count int) string {
+	var level *FeedbackLevel
+	for i := range f.Levels {
+		l := &f.Levels[i]
+		if l.MinCount <= count && (level == nil || l.MinCount > level.MinCount) {
+			level = l
+		}
+	}
+	if level == nil {
+		return ""
+	}
+
+	format := f.FormatCount
+	if format == nil {
+		format = strconv.Itoa
+	}
+	return strings.ReplaceAll(level.Message, "{count}", format(count))
+}
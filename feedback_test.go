@@ -0,0 +1,72 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestFeedbackMessage(t *testing.T) {
+	custom := exposed.Feedback{
+		Levels: []exposed.FeedbackLevel{
+			{MinCount: 100, Message: "Sehr häufig: {count}"},
+			{MinCount: 1, Message: "Gefunden: {count}"},
+		},
+		FormatCount: func(count int) string { return "#" + string(rune('0'+count%10)) },
+	}
+
+	tests := []struct {
+		name     string
+		feedback exposed.Feedback
+		count    int
+		want     string
+	}{
+		{
+			name:     "default not found",
+			feedback: exposed.DefaultFeedback,
+			count:    0,
+			want:     "This password has not appeared in any known breaches.",
+		},
+		{
+			name:     "default found",
+			feedback: exposed.DefaultFeedback,
+			count:    42,
+			want:     "This password has appeared in 42 breaches; choose another.",
+		},
+		{
+			name:     "default very common",
+			feedback: exposed.DefaultFeedback,
+			count:    10434004,
+			want:     "This password is very common and has appeared in 10434004 breaches; choose another.",
+		},
+		{
+			name:     "custom below all levels",
+			feedback: custom,
+			count:    0,
+			want:     "",
+		},
+		{
+			name:     "custom unordered levels",
+			feedback: custom,
+			count:    5,
+			want:     "Gefunden: #5",
+		},
+		{
+			name:     "custom highest level",
+			feedback: custom,
+			count:    102,
+			want:     "Sehr häufig: #2",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.feedback.Message(tc.count)
+			if got != tc.want {
+				t.Errorf("Message(%d) = %q, expected %q", tc.count, got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// FixtureTransport is an http.RoundTripper that serves canned range
+// responses from memory instead of the network, so examples and tests of
+// code using a PwnedClient run hermetically. It is not needed for normal
+// use.
+//
+// The prefix is taken from the last element of the request path, so any
+// base URL can be used, e.g., BaseURL. Prefixes without a fixture are served
+// as empty ranges, i.e., every hash with the prefix is not found.
+type FixtureTransport struct {
+	// SHA1 maps five character uppercase prefixes to SHA-1 range bodies of
+	// "SUFFIX:count" lines.
+	SHA1 map[string]string
+
+	// NTLM maps five character uppercase prefixes to NTLM range bodies.
+	NTLM map[string]string
+}
+
+// RoundTrip returns the fixture for the range requested by req.
+func (ft *FixtureTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	ranges := ft.SHA1
+	if req.URL.Query().Get("mode") == "ntlm" {
+		ranges = ft.NTLM
+	}
+	body := ranges[strings.ToUpper(path.Base(req.URL.Path))]
+
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"text/plain"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
@@ -0,0 +1,36 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestFixtureTransport(t *testing.T) {
+	transport := &exposed.FixtureTransport{
+		SHA1: map[string]string{"5BAA6": readFile("testdata/5BAA6")},
+		NTLM: map[string]string{"8846F": readFile("testdata/8846F")},
+	}
+	c := exposed.NewPwnedClient(&http.Client{Transport: transport}, exposed.BaseURL)
+
+	tests := []struct {
+		password, mode string
+		want           int
+	}{
+		{"password", "sha1", 10434004},
+		{"password", "ntlm", 10434004},
+		{"not in a fixture", "sha1", 0},
+	}
+	for _, tc := range tests {
+		count, err := c.CheckPwnedPassword(tc.password, tc.mode)
+		if err != nil {
+			t.Fatalf("CheckPwnedPassword(%q, %q) error = %v", tc.password, tc.mode, err)
+		}
+		if count != tc.want {
+			t.Errorf("CheckPwnedPassword(%q, %q) = %d, expected %d", tc.password, tc.mode, count, tc.want)
+		}
+	}
+}
@@ -3,8 +3,12 @@ module github.com/bnixon67/exposed
 go 1.22.4
 
 require (
+	github.com/klauspost/compress v1.17.9
+	github.com/ulikunitz/xz v0.5.12
 	golang.org/x/crypto v0.26.0
 	golang.org/x/term v0.23.0
+	golang.org/x/text v0.17.0
+	golang.org/x/time v0.6.0
 )
 
 require golang.org/x/sys v0.24.0 // indirect
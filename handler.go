@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// NewRangeHandler returns an http.Handler that implements the range API
+// using c, so it can act as a caching proxy in front of the Pwned Passwords
+// API or a mirror when c is configured with WithCache.
+//
+// The prefix is taken from the last element of the request path, e.g.,
+// "/range/5BAA6", and the optional "mode=ntlm" query parameter selects NTLM
+// hashes. Upstream failures are reported as 502 Bad Gateway.
+func NewRangeHandler(c *PwnedClient) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		prefix := strings.ToUpper(path.Base(r.URL.Path))
+		if err := c.validatePrefix(prefix); err != nil {
+			http.Error(w, "the hash prefix was not in a valid format", http.StatusBadRequest)
+			return
+		}
+
+		mode := "sha1"
+		switch m := r.URL.Query().Get("mode"); m {
+		case "":
+		case "ntlm":
+			mode = m
+		default:
+			http.Error(w, "invalid mode", http.StatusBadRequest)
+			return
+		}
+
+		body, contentType, _, err := c.openRange(r.Context(), prefix, mode)
+		if err != nil {
+			http.Error(w, "upstream request failed", http.StatusBadGateway)
+			return
+		}
+		defer body.Close()
+
+		if contentType == "" {
+			contentType = "text/plain"
+		}
+		w.Header().Set("Content-Type", contentType)
+		if r.Method == http.MethodHead {
+			return
+		}
+		_, _ = io.Copy(w, body)
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestRangeHandler(t *testing.T) {
+	upstreamRequests := 0
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamRequests++
+		switch r.URL.Path {
+		case "/range/5BAA6":
+			_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+		case "/range/8846F":
+			if r.URL.Query().Get("mode") != "ntlm" {
+				t.Errorf("mode = %q, expected ntlm", r.URL.Query().Get("mode"))
+			}
+			_, _ = w.Write([]byte(readFile("testdata/8846F")))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer upstream.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, upstream.URL+"/range", exposed.WithCache(10, time.Hour))
+	proxy := httptest.NewServer(exposed.NewRangeHandler(c))
+	defer proxy.Close()
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "SHA-1", path: "/range/5BAA6", wantStatus: http.StatusOK, wantBody: readFile("testdata/5BAA6")},
+		{name: "SHA-1 cached", path: "/range/5baa6", wantStatus: http.StatusOK, wantBody: readFile("testdata/5BAA6")},
+		{name: "NTLM", path: "/range/8846F?mode=ntlm", wantStatus: http.StatusOK, wantBody: readFile("testdata/8846F")},
+		{name: "invalid prefix", path: "/range/5BAA", wantStatus: http.StatusBadRequest},
+		{name: "non-hex prefix", path: "/range/ZZZZZ", wantStatus: http.StatusBadRequest},
+		{name: "invalid mode", path: "/range/5BAA6?mode=md5", wantStatus: http.StatusBadRequest},
+		{name: "upstream error", path: "/range/00000", wantStatus: http.StatusBadGateway},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, err := http.Get(proxy.URL + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tc.wantStatus {
+				t.Errorf("status = %d, expected %d", resp.StatusCode, tc.wantStatus)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			b, _ := io.ReadAll(resp.Body)
+			if string(b) != tc.wantBody {
+				t.Errorf("body differs from upstream")
+			}
+		})
+	}
+
+	// 5BAA6 was served from the cache the second time
+	if upstreamRequests != 3 {
+		t.Errorf("upstream requests = %d, expected %d", upstreamRequests, 3)
+	}
+}
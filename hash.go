@@ -0,0 +1,51 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// HashEncoding selects how Hash represents a digest.
+type HashEncoding int
+
+const (
+	// HexEncoding is uppercase hex, the format used by the API.
+	HexEncoding HashEncoding = iota
+
+	// Base64Encoding is standard base64 with padding.
+	Base64Encoding
+
+	// Base64URLEncoding is URL-safe base64 with padding.
+	Base64URLEncoding
+)
+
+// Hash returns the hash of type mode of password in the encoding enc, e.g.,
+// for tools that store hashes in base64. The password is hashed as given,
+// without the normalization applied by a PwnedClient. Requests to the API
+// always use uppercase hex regardless of how hashes are returned here.
+func Hash(password, mode string, enc HashEncoding) (string, error) {
+	var digest []byte
+	switch mode {
+	case "ntlm":
+		digest = ntDigest(password)
+	case "sha1":
+		digest = sha1Digest(password)
+	default:
+		return "", fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+
+	switch enc {
+	case HexEncoding:
+		return strings.ToUpper(hex.EncodeToString(digest)), nil
+	case Base64Encoding:
+		return base64.StdEncoding.EncodeToString(digest), nil
+	case Base64URLEncoding:
+		return base64.URLEncoding.EncodeToString(digest), nil
+	default:
+		return "", fmt.Errorf("invalid hash encoding: %d", enc)
+	}
+}
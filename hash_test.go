@@ -0,0 +1,66 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import "testing"
+
+func TestNTHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		password string
+		want     string
+	}{
+		{"empty", "", "31D6CFE0D16AE931B73C59D7E0C089C0"},
+		{"lowercase", "password", "8846F7EAEE8FB117AD06BDD830B7586C"},
+		{"mixed case", "Password", "A4F49C406510BDCAB6824EE7C30FD852"},
+		{"digits", "123456", "32ED87BDB5FDC5E9CBA88547376818D4"},
+		{"latin-1", "pässwörd", "0553152250AC01ADB4213CB9938663E4"},
+		{"cjk", "日本語", "CED13822047F22CE2B3E7D763955F48E"},
+		{"surrogate pair", "\U0001F600", "4B58A10CC20A4E7D808D218E1F80AABC"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := ntHash(tc.password)
+			if got != tc.want {
+				t.Errorf("ntHash(%q) = %s, expected %s", tc.password, got, tc.want)
+			}
+			if err := validateHash(got, "ntlm"); err != nil {
+				t.Errorf("ntHash(%q) is not a valid NTLM hash: %v", tc.password, err)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	tests := []struct {
+		password string
+		mode     string
+		enc      HashEncoding
+		want     string
+		wantErr  bool
+	}{
+		{"password", "sha1", HexEncoding, "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", false},
+		{"password", "sha1", Base64Encoding, "W6ph5Mm5Pz8GgiULbPgzG37mj9g=", false},
+		{"password", "sha1", Base64URLEncoding, "W6ph5Mm5Pz8GgiULbPgzG37mj9g=", false},
+		{"abc", "sha1", Base64Encoding, "qZk+NkcGgWq6PiVxeFDCbJzQ2J0=", false},
+		{"abc", "sha1", Base64URLEncoding, "qZk-NkcGgWq6PiVxeFDCbJzQ2J0=", false},
+		{"secret", "sha1", Base64Encoding, "5en6G6MezRroT3XKqkdPOmY/BfQ=", false},
+		{"secret", "sha1", Base64URLEncoding, "5en6G6MezRroT3XKqkdPOmY_BfQ=", false},
+		{"password", "ntlm", HexEncoding, "8846F7EAEE8FB117AD06BDD830B7586C", false},
+		{"password", "ntlm", Base64Encoding, "iEb36u6PsRetBr3YMLdYbA==", false},
+		{"password", "ntlm", Base64URLEncoding, "iEb36u6PsRetBr3YMLdYbA==", false},
+		{"password", "md5", HexEncoding, "", true},
+		{"password", "sha1", HashEncoding(99), "", true},
+	}
+
+	for _, tc := range tests {
+		got, err := Hash(tc.password, tc.mode, tc.enc)
+		if (err != nil) != tc.wantErr {
+			t.Fatalf("Hash(%q, %q, %d) error = %v, expectedErr %v", tc.password, tc.mode, tc.enc, err, tc.wantErr)
+		}
+		if got != tc.want {
+			t.Errorf("Hash(%q, %q, %d) = %q, expected %q", tc.password, tc.mode, tc.enc, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+)
+
+// BlockedCount is the count reported for hashes in a client's blocklist. It
+// is larger than any real count so that blocked inputs are rejected by any
+// threshold.
+const BlockedCount = math.MaxInt32
+
+// HashList is a set of SHA-1 and NTLM hashes used to match inputs without
+// storing them in plain text.
+type HashList struct {
+	hashes map[string]map[string]bool // mode to set of uppercase hashes
+}
+
+// NewHashList returns an empty HashList.
+func NewHashList() *HashList {
+	return &HashList{hashes: make(map[string]map[string]bool)}
+}
+
+// add adds hash of type mode without validation.
+func (l *HashList) add(hash, mode string) {
+	set, ok := l.hashes[mode]
+	if !ok {
+		set = make(map[string]bool)
+		l.hashes[mode] = set
+	}
+	set[hash] = true
+}
+
+// AddHash adds a SHA-1 or NTLM hash to the list. The mode is determined by
+// the length of the hash. Case is ignored.
+func (l *HashList) AddHash(hash string) error {
+	hash = strings.ToUpper(hash)
+	for mode, n := range hashLengths {
+		if len(hash) == n {
+			if err := validateHash(hash, mode); err != nil {
+				return err
+			}
+			l.add(hash, mode)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q has invalid length %d", ErrInvalidHash, hash, len(hash))
+}
+
+// AddPassword adds the SHA-1 and NTLM hashes of password to the list. Only
+// the hashes are kept. The password is hashed as given, so it must match the
+// normalization used by the client.
+func (l *HashList) AddPassword(password string) {
+	l.add(sha1Hash(password), "sha1")
+	l.add(ntHash(password), "ntlm")
+}
+
+// Contains reports whether hash of type mode is in the list. Case is
+// ignored.
+func (l *HashList) Contains(hash, mode string) bool {
+	return l.hashes[mode][strings.ToUpper(hash)]
+}
+
+// Len returns the number of hashes in the list.
+func (l *HashList) Len() int {
+	n := 0
+	for _, set := range l.hashes {
+		n += len(set)
+	}
+	return n
+}
+
+// ReadHashList reads a HashList from r, which contains one SHA-1 or NTLM
+// hash per line. Blank lines and lines starting with "#" are ignored.
+func ReadHashList(r io.Reader) (*HashList, error) {
+	l := NewHashList()
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := l.AddHash(line); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// WithAllowlist sets a HashList of hashes that are always reported as not
+// found without a network request, e.g., for documented test accounts. The
+// list must not be modified while in use by the client.
+func WithAllowlist(l *HashList) Option {
+	return func(c *PwnedClient) {
+		c.allowlist = l
+	}
+}
+
+// WithBlocklist sets a HashList of hashes that are always reported as
+// exposed BlockedCount times without a network request, e.g., to reject
+// company-specific words not yet in the Pwned Passwords dataset. The
+// blocklist takes precedence over the allowlist. The list must not be
+// modified while in use by the client.
+func WithBlocklist(l *HashList) Option {
+	return func(c *PwnedClient) {
+		c.blocklist = l
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestReadHashList(t *testing.T) {
+	input := `# test accounts
+5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8
+
+8846F7EAEE8FB117AD06BDD830B7586C
+`
+	l, err := exposed.ReadHashList(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ReadHashList() error = %v", err)
+	}
+	if l.Len() != 2 {
+		t.Errorf("Len() = %d, expected 2", l.Len())
+	}
+
+	tests := []struct {
+		hash, mode string
+		want       bool
+	}{
+		{"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", "sha1", true},
+		{"8846f7eaee8fb117ad06bdd830b7586c", "ntlm", true},
+		{"8846F7EAEE8FB117AD06BDD830B7586C", "sha1", false},
+		{"0000000000000000000000000000000000000000", "sha1", false},
+	}
+	for _, tc := range tests {
+		if got := l.Contains(tc.hash, tc.mode); got != tc.want {
+			t.Errorf("Contains(%q, %q) = %v, expected %v", tc.hash, tc.mode, got, tc.want)
+		}
+	}
+
+	_, err = exposed.ReadHashList(strings.NewReader("5BAA6\n"))
+	if !errors.Is(err, exposed.ErrInvalidHash) {
+		t.Errorf("ReadHashList() error = %v, expected %v", err, exposed.ErrInvalidHash)
+	}
+}
+
+func TestWithAllowlist(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	l := exposed.NewHashList()
+	l.AddPassword("password")
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithAllowlist(l))
+
+	for _, mode := range exposed.ValidHashes {
+		count, err := c.CheckPwnedPassword("password", mode)
+		if err != nil {
+			t.Fatalf("CheckPwnedPassword(%q) error = %v", mode, err)
+		}
+		if count != 0 {
+			t.Errorf("CheckPwnedPassword(%q) = %d, expected 0", mode, count)
+		}
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests, expected 0", requests)
+	}
+
+	if _, err := c.CheckPwnedPassword("other", "sha1"); err != nil {
+		t.Fatalf("CheckPwnedPassword() error = %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("got %d requests, expected 1", requests)
+	}
+}
+
+func TestWithBlocklist(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	blocked := exposed.NewHashList()
+	blocked.AddPassword("acme2024")
+	blocked.AddPassword("password")
+	allowed := exposed.NewHashList()
+	allowed.AddPassword("password")
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithBlocklist(blocked), exposed.WithAllowlist(allowed))
+
+	for _, password := range []string{"acme2024", "password"} {
+		count, err := c.CheckPwnedPassword(password, "ntlm")
+		if err != nil {
+			t.Fatalf("CheckPwnedPassword(%q) error = %v", password, err)
+		}
+		if count != exposed.BlockedCount {
+			t.Errorf("CheckPwnedPassword(%q) = %d, expected %d", password, count, exposed.BlockedCount)
+		}
+	}
+
+	hash := "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	result, err := c.CheckPwnedHashWithResult(hash, "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedHashWithResult() error = %v", err)
+	}
+	if !result.Blocked || result.Count != exposed.BlockedCount {
+		t.Errorf("CheckPwnedHashWithResult() = %+v, expected blocked", result)
+	}
+	if requests != 0 {
+		t.Errorf("got %d requests, expected 0", requests)
+	}
+}
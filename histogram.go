@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// latencyBuckets is the number of buckets in a latency histogram. Bucket 0
+// counts latencies under 1µs and bucket i counts latencies from 2^(i-1)µs up
+// to 2^iµs, with the last bucket also counting anything longer.
+const latencyBuckets = 40
+
+// latencyHistogram records latencies into exponential buckets using atomic
+// counters, so recording never blocks and adds little to what it measures.
+type latencyHistogram struct {
+	counts [latencyBuckets]atomic.Int64
+	sum    atomic.Int64 // nanoseconds
+}
+
+// bucketFor returns the index of the bucket counting d.
+func bucketFor(d time.Duration) int {
+	if d < 0 {
+		d = 0
+	}
+	i := bits.Len64(uint64(d / time.Microsecond))
+	return min(i, latencyBuckets-1)
+}
+
+// bucketBound returns the upper bound of bucket i.
+func bucketBound(i int) time.Duration {
+	return time.Duration(1<<i) * time.Microsecond
+}
+
+// record adds d to the histogram.
+func (h *latencyHistogram) record(d time.Duration) {
+	h.counts[bucketFor(d)].Add(1)
+	h.sum.Add(int64(d))
+}
+
+// LatencyBucket is a bucket of a LatencyHistogram.
+type LatencyBucket struct {
+	UpperBound time.Duration // latencies in the bucket are less than this
+	Count      int64
+}
+
+// LatencyHistogram is a snapshot of the upstream request latencies of a
+// client in exponential buckets, each twice as wide as the one before.
+type LatencyHistogram struct {
+	// Buckets holds the counts from the shortest latencies up to the
+	// longest bucket with a count. The last possible bucket also counts
+	// latencies beyond its bound.
+	Buckets []LatencyBucket
+
+	Count int64         // number of latencies recorded
+	Sum   time.Duration // total of the latencies recorded
+}
+
+// Latencies returns a histogram of the time taken by each upstream request
+// attempt, including retries and failures, from sending the request to
+// receiving the response headers. Time waiting on the rate limiter or
+// between retries is not included. It is safe to call concurrently with
+// lookups, though a snapshot taken during lookups may be slightly
+// inconsistent.
+func (c *PwnedClient) Latencies() LatencyHistogram {
+	var h LatencyHistogram
+	if c.stats == nil {
+		return h
+	}
+
+	last := -1
+	var counts [latencyBuckets]int64
+	for i := range counts {
+		counts[i] = c.stats.latency.counts[i].Load()
+		if counts[i] > 0 {
+			last = i
+		}
+	}
+
+	for i := 0; i <= last; i++ {
+		h.Buckets = append(h.Buckets, LatencyBucket{UpperBound: bucketBound(i), Count: counts[i]})
+		h.Count += counts[i]
+	}
+	h.Sum = time.Duration(c.stats.latency.sum.Load())
+	return h
+}
+
+// Mean returns the average latency, or zero if none were recorded.
+func (h LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Sum / time.Duration(h.Count)
+}
+
+// Percentile returns an upper bound on the p-th percentile latency, for p
+// from 0 to 100, i.e., the upper bound of the bucket holding the latency at
+// that rank, so it overestimates by less than a factor of two. It returns
+// zero if no latencies were recorded.
+func (h LatencyHistogram) Percentile(p float64) time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+
+	rank := int64(math.Ceil(p / 100 * float64(h.Count)))
+	rank = max(rank, 1)
+
+	var seen int64
+	for _, b := range h.Buckets {
+		seen += b.Count
+		if seen >= rank {
+			return b.UpperBound
+		}
+	}
+	return h.Buckets[len(h.Buckets)-1].UpperBound
+}
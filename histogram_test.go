@@ -0,0 +1,77 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencies(t *testing.T) {
+	c := NewPwnedClient(nil, BaseURL)
+
+	if h := c.Latencies(); h.Count != 0 || h.Percentile(50) != 0 || h.Mean() != 0 {
+		t.Errorf("empty Latencies() = %+v, expected no latencies", h)
+	}
+
+	// 90 fast requests and 10 slow ones
+	for i := 0; i < 90; i++ {
+		c.stats.latency.record(3 * time.Millisecond)
+	}
+	for i := 0; i < 10; i++ {
+		c.stats.latency.record(700 * time.Millisecond)
+	}
+
+	h := c.Latencies()
+	if h.Count != 100 {
+		t.Errorf("Count = %d, expected %d", h.Count, 100)
+	}
+	if want := 72700 * time.Microsecond; h.Mean() != want {
+		t.Errorf("Mean() = %v, expected %v", h.Mean(), want)
+	}
+
+	tests := []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, 4096 * time.Microsecond},
+		{50, 4096 * time.Microsecond},
+		{90, 4096 * time.Microsecond},
+		{91, 1048576 * time.Microsecond},
+		{100, 1048576 * time.Microsecond},
+	}
+	for _, tc := range tests {
+		if got := h.Percentile(tc.p); got != tc.want {
+			t.Errorf("Percentile(%v) = %v, expected %v", tc.p, got, tc.want)
+		}
+	}
+
+	last := h.Buckets[len(h.Buckets)-1]
+	if last.UpperBound != 1048576*time.Microsecond || last.Count != 10 {
+		t.Errorf("last bucket = %+v, expected 10 under %v", last, 1048576*time.Microsecond)
+	}
+}
+
+func TestBucketFor(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{-time.Second, 0},
+		{0, 0},
+		{999 * time.Nanosecond, 0},
+		{time.Microsecond, 1},
+		{2 * time.Microsecond, 2},
+		{3 * time.Microsecond, 2},
+		{4 * time.Microsecond, 3},
+		{1000 * time.Hour, latencyBuckets - 1},
+	}
+	for _, tc := range tests {
+		if got := bucketFor(tc.d); got != tc.want {
+			t.Errorf("bucketFor(%v) = %d, expected %d", tc.d, got, tc.want)
+		}
+		if tc.d >= 0 && tc.want < latencyBuckets-1 && tc.d >= bucketBound(tc.want) {
+			t.Errorf("%v is not below the bound of bucket %d, %v", tc.d, tc.want, bucketBound(tc.want))
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// IndexProgress reports the progress of BuildRangeIndex.
+type IndexProgress struct {
+	Lines    int64 // lines read from the dump
+	Prefixes int   // prefix files written
+}
+
+// indexProgressInterval is the number of lines between progress reports.
+const indexProgressInterval = 1_000_000
+
+// rangeFile is the prefix file currently being written by BuildRangeIndex.
+type rangeFile struct {
+	prefix string
+	f      *os.File
+	w      *bufio.Writer
+}
+
+// close flushes and closes the file.
+func (rf *rangeFile) close() error {
+	if rf.f == nil {
+		return nil
+	}
+	err := rf.w.Flush()
+	if cerr := rf.f.Close(); err == nil {
+		err = cerr
+	}
+	rf.f = nil
+	return err
+}
+
+// BuildRangeIndex reads a Pwned Passwords dump of "HASH:count" lines for
+// hashes of type mode from r and writes a file per prefix of DefaultPrefixLength
+// characters to dir. Each file is named by its prefix and contains "SUFFIX:count" lines,
+// the same format returned by the range API, so dir can be used as an
+// offline store, e.g., by serving it with http.FileServer or by creating a
+// client with http.NewFileTransport(http.Dir(dir)) and a "file:///" base
+// URL.
+//
+// The official dump is sorted by hash, so each prefix file is written in one
+// pass, but unsorted input is also handled. Since the dump is tens of
+// gigabytes, progress, if not nil, is called periodically and when the index
+// is complete.
+func BuildRangeIndex(r io.Reader, dir, mode string, progress func(IndexProgress)) error {
+	want, ok := hashLengths[mode]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	var (
+		p    IndexProgress
+		cur  rangeFile
+		seen = make(map[string]bool)
+	)
+	defer cur.close()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		p.Lines++
+		if progress != nil && p.Lines%indexProgressInterval == 0 {
+			progress(p)
+		}
+
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hash, count, found := strings.Cut(line, ":")
+		hash = strings.ToUpper(hash)
+		if !found || len(hash) != want {
+			return fmt.Errorf("line %d: invalid %s dump line: %q", p.Lines, mode, line)
+		}
+		if err := validateHash(hash, mode); err != nil {
+			return fmt.Errorf("line %d: %w", p.Lines, err)
+		}
+		if _, err := strconv.Atoi(count); err != nil {
+			return fmt.Errorf("line %d: invalid count: %w", p.Lines, err)
+		}
+
+		prefix := hash[:DefaultPrefixLength]
+		if prefix != cur.prefix || cur.f == nil {
+			if err := cur.close(); err != nil {
+				return err
+			}
+
+			flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+			if seen[prefix] {
+				flag = os.O_WRONLY | os.O_APPEND
+			} else {
+				p.Prefixes++
+			}
+			seen[prefix] = true
+
+			f, err := os.OpenFile(filepath.Join(dir, prefix), flag, 0o644)
+			if err != nil {
+				return err
+			}
+			cur = rangeFile{prefix: prefix, f: f, w: bufio.NewWriter(f)}
+		}
+
+		if _, err := fmt.Fprintf(cur.w, "%s:%s\r\n", hash[5:], count); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if err := cur.close(); err != nil {
+		return err
+	}
+	if progress != nil {
+		progress(p)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestBuildRangeIndex(t *testing.T) {
+	dump := strings.Join([]string{
+		"000000005AD76BD555C1D6D771DE417A4B87E4B4:10",
+		"00000000A8DAE4228F821FB418F59826079BF368:4",
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004",
+		"0000000CAEF405439D57847A8657218C618160B2:15",
+	}, "\r\n") + "\r\n"
+
+	dir := t.TempDir()
+	var last exposed.IndexProgress
+	err := exposed.BuildRangeIndex(strings.NewReader(dump), dir, "sha1", func(p exposed.IndexProgress) {
+		last = p
+	})
+	if err != nil {
+		t.Fatalf("BuildRangeIndex() error = %v", err)
+	}
+
+	if last.Lines != 4 || last.Prefixes != 2 {
+		t.Errorf("progress = %+v, expected 4 lines and 2 prefixes", last)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "00000"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "0005AD76BD555C1D6D771DE417A4B87E4B4:10\r\n" +
+		"000A8DAE4228F821FB418F59826079BF368:4\r\n" +
+		"00CAEF405439D57847A8657218C618160B2:15\r\n"
+	if string(b) != want {
+		t.Errorf("00000 = %q, expected %q", b, want)
+	}
+
+	// the index can be used as an offline store
+	c := exposed.NewPwnedClient(&http.Client{Transport: http.NewFileTransport(http.Dir(dir))}, "file:///")
+	count, err := c.CheckPwnedPassword("password", "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedPassword() error = %v", err)
+	}
+	if count != 10434004 {
+		t.Errorf("CheckPwnedPassword() = %v, expected %v", count, 10434004)
+	}
+}
+
+func TestBuildRangeIndexInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		dump string
+		mode string
+	}{
+		{name: "invalid mode", dump: "", mode: "md5"},
+		{name: "missing count", dump: "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\n", mode: "sha1"},
+		{name: "invalid count", dump: "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8:x\n", mode: "sha1"},
+		{name: "wrong length", dump: "8846F7EAEE8FB117AD06BDD830B7586C:1\n", mode: "sha1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := exposed.BuildRangeIndex(strings.NewReader(tc.dump), t.TempDir(), tc.mode, nil)
+			if err == nil {
+				t.Error("BuildRangeIndex() error = nil, expected error")
+			}
+		})
+	}
+}
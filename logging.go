@@ -0,0 +1,143 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+)
+
+// WithLogger sets a logger for debugging requests, retries, and cache use.
+// Everything logged by the client passes through a handler that redacts
+// full hashes to their prefix with Redact, and passwords are never logged,
+// so logs are safe to collect. By default, nothing is logged.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *PwnedClient) {
+		if logger == nil {
+			c.logger = nil
+			return
+		}
+		c.logger = slog.New(&redactHandler{h: logger.Handler()})
+	}
+}
+
+// Redact returns s with everything after the DefaultPrefixLength character
+// prefix sent to the API replaced, e.g., "5BAA6..." for a SHA-1 hash.
+// Strings of DefaultPrefixLength or fewer characters are returned unchanged.
+func Redact(s string) string {
+	if len(s) <= DefaultPrefixLength {
+		return s
+	}
+	return s[:DefaultPrefixLength] + "..."
+}
+
+// looksLikeHash reports whether s has the length of a known hash type and
+// contains only hex digits.
+func looksLikeHash(s string) bool {
+	for _, n := range hashLengths {
+		if len(s) == n {
+			return strings.IndexFunc(strings.ToUpper(s), notUpperHex) < 0
+		}
+	}
+	return false
+}
+
+// redactHandler is a slog.Handler that redacts full hashes in messages and
+// attributes before passing records to the wrapped handler. It is the
+// single point through which the client logs.
+type redactHandler struct {
+	h slog.Handler
+}
+
+func (rh *redactHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return rh.h.Enabled(ctx, level)
+}
+
+func (rh *redactHandler) Handle(ctx context.Context, r slog.Record) error {
+	nr := slog.NewRecord(r.Time, r.Level, redactText(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		nr.AddAttrs(redactAttr(a))
+		return true
+	})
+	return rh.h.Handle(ctx, nr)
+}
+
+func (rh *redactHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redacted := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		redacted[i] = redactAttr(a)
+	}
+	return &redactHandler{h: rh.h.WithAttrs(redacted)}
+}
+
+func (rh *redactHandler) WithGroup(name string) slog.Handler {
+	return &redactHandler{h: rh.h.WithGroup(name)}
+}
+
+// redactAttr returns a with any full hashes in string values redacted,
+// including within groups.
+func redactAttr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, redactText(v.String()))
+	case slog.KindGroup:
+		attrs := v.Group()
+		redacted := make([]any, len(attrs))
+		for i, ga := range attrs {
+			redacted[i] = redactAttr(ga)
+		}
+		return slog.Group(a.Key, redacted...)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return slog.String(a.Key, redactText(err.Error()))
+		}
+		return slog.String(a.Key, redactText(v.String()))
+	default:
+		return slog.Attr{Key: a.Key, Value: v}
+	}
+}
+
+// redactText returns s with every hex run that looks like a full hash
+// replaced by its redacted form.
+func redactText(s string) string {
+	var b strings.Builder
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		if run := s[start:end]; looksLikeHash(run) {
+			b.WriteString(Redact(run))
+		} else {
+			b.WriteString(run)
+		}
+		start = -1
+	}
+
+	for i := 0; i < len(s); i++ {
+		if isHexDigit(s[i]) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+		b.WriteByte(s[i])
+	}
+	flush(len(s))
+	return b.String()
+}
+
+// isHexDigit reports whether c is a hex digit of either case.
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// logDebug logs msg at debug level with args if the client has a logger.
+func (c *PwnedClient) logDebug(ctx context.Context, msg string, args ...any) {
+	if c.logger != nil {
+		c.logger.DebugContext(ctx, msg, args...)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8", "5BAA6..."},
+		{"hash=5baa61e4c9b93f3f0682250b6cf8331b7ee68fd8!", "hash=5baa6...!"},
+		{"ntlm 8846F7EAEE8FB117AD06BDD830B7586C ok", "ntlm 8846F... ok"},
+		{"prefix 5BAA6", "prefix 5BAA6"},
+		{"status 503 after 1s", "status 503 after 1s"},
+	}
+
+	for _, tc := range tests {
+		if got := redactText(tc.in); got != tc.want {
+			t.Errorf("redactText(%q) = %q, expected %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestWithLoggerRedacts(t *testing.T) {
+	const (
+		password = "password"
+		hash     = "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"
+	)
+
+	body, err := os.ReadFile("testdata/5BAA6")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	c := NewPwnedClient(&http.Client{}, server.URL,
+		WithLogger(logger),
+		WithCache(10, 0),
+		WithRetryPolicy(RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond}))
+
+	for i := 0; i < 2; i++ {
+		if _, err := c.CheckPwnedPassword(password, "sha1"); err != nil {
+			t.Fatalf("CheckPwnedPassword() error = %v", err)
+		}
+	}
+
+	// log a full hash directly to exercise the handler
+	c.logDebug(context.Background(), "hash "+hash, "hash", hash,
+		"error", errors.New("bad "+hash), slog.Group("g", "hash", hash))
+
+	out := buf.String()
+	for _, secret := range []string{password, hash, strings.ToLower(hash), hash[5:]} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log output contains %q:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{"retrying request", "range request", "range served from cache", "5BAA6"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("log output missing %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// PasswordPolicy configures RejectPwnedPassword.
+type PasswordPolicy struct {
+	// Field is the form field holding the password, "password" if empty.
+	Field string
+
+	// Mode is the hash mode used to check the password, "sha1" if empty.
+	Mode string
+
+	// Threshold is how common is too common: a password exposed more than
+	// Threshold times is rejected, consistent with IsCompromised. The
+	// default of 0 rejects any exposed password.
+	Threshold int
+
+	// FailOpen passes requests to the next handler if the password cannot
+	// be checked, e.g., if the API is unreachable, instead of rejecting
+	// them with 503 Service Unavailable.
+	FailOpen bool
+
+	// Feedback provides the message of a rejection, DefaultFeedback if nil.
+	Feedback *Feedback
+}
+
+// PasswordRejection is the JSON body of a response from RejectPwnedPassword
+// that rejected a request.
+type PasswordRejection struct {
+	// Error is "pwned_password" if the password is too common,
+	// "blocked_password" if it matched the client's blocklist, or
+	// "check_failed" if it could not be checked.
+	Error string `json:"error"`
+
+	// Message describes why the password was rejected, e.g., to show to
+	// the user.
+	Message string `json:"message"`
+
+	// Count and Threshold are the number of times the password was exposed
+	// and the policy threshold it exceeded, set only for "pwned_password".
+	Count     int `json:"count,omitempty"`
+	Threshold int `json:"threshold,omitempty"`
+}
+
+// RejectPwnedPassword returns middleware that checks the password in the
+// policy's form field of each request using c and rejects the request with
+// 422 Unprocessable Entity if the password was exposed more than the
+// policy's threshold or matched the client's blocklist, see WithBlocklist,
+// otherwise the request is passed to next. Requests
+// without the field are passed to next, which is responsible for requiring
+// it. Only the request body is read for the field, never the URL query.
+//
+// If the password cannot be checked, the request is passed to next if the
+// policy fails open, otherwise it is rejected with 503 Service Unavailable.
+// A rejection has a PasswordRejection as its JSON body.
+func RejectPwnedPassword(c *PwnedClient, policy PasswordPolicy, next http.Handler) http.Handler {
+	field := policy.Field
+	if field == "" {
+		field = "password"
+	}
+	mode := policy.Mode
+	if mode == "" {
+		mode = "sha1"
+	}
+	feedback := policy.Feedback
+	if feedback == nil {
+		feedback = &DefaultFeedback
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		password := r.PostFormValue(field)
+		if password == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		result, err := c.CheckPwnedWithResultContext(r.Context(), password, "password", mode)
+		count := result.Count
+		switch {
+		case err != nil && policy.FailOpen:
+			c.logDebug(r.Context(), "password check failed, failing open", "error", err)
+			next.ServeHTTP(w, r)
+		case err != nil:
+			c.logDebug(r.Context(), "password check failed, failing closed", "error", err)
+			writeRejection(w, http.StatusServiceUnavailable, PasswordRejection{
+				Error:   "check_failed",
+				Message: "The password could not be checked; try again later.",
+			})
+		case result.Blocked:
+			// BlockedCount is not a real count, so it is not shown
+			writeRejection(w, http.StatusUnprocessableEntity, PasswordRejection{
+				Error:   "blocked_password",
+				Message: "This password is not allowed; choose another.",
+			})
+		case count > policy.Threshold:
+			writeRejection(w, http.StatusUnprocessableEntity, PasswordRejection{
+				Error:     "pwned_password",
+				Message:   feedback.Message(count),
+				Count:     count,
+				Threshold: policy.Threshold,
+			})
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// writeRejection writes rejection as the JSON body of a response with
+// status.
+func writeRejection(w http.ResponseWriter, status int, rejection PasswordRejection) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(rejection)
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestRejectPwnedPassword(t *testing.T) {
+	fixture := &exposed.FixtureTransport{
+		SHA1: map[string]string{"5BAA6": readFile("testdata/5BAA6")},
+	}
+	client := exposed.NewPwnedClient(&http.Client{Transport: fixture}, exposed.BaseURL)
+
+	blocklist := exposed.NewHashList()
+	blocklist.AddPassword("acme")
+	blocking := exposed.NewPwnedClient(&http.Client{Transport: fixture}, exposed.BaseURL,
+		exposed.WithBlocklist(blocklist))
+
+	// a server that is closed cannot be reached
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+	failing := exposed.NewPwnedClient(&http.Client{}, down.URL)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	tests := []struct {
+		name       string
+		client     *exposed.PwnedClient
+		policy     exposed.PasswordPolicy
+		form       url.Values
+		wantStatus int
+		want       exposed.PasswordRejection
+	}{
+		{
+			name:       "exposed",
+			client:     client,
+			form:       url.Values{"password": {"password"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			want: exposed.PasswordRejection{
+				Error:   "pwned_password",
+				Message: exposed.DefaultFeedback.Message(10434004),
+				Count:   10434004,
+			},
+		},
+		{
+			name:       "below threshold",
+			client:     client,
+			policy:     exposed.PasswordPolicy{Threshold: 10434004},
+			form:       url.Values{"password": {"password"}},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "above threshold",
+			client:     client,
+			policy:     exposed.PasswordPolicy{Field: "new", Threshold: 1000},
+			form:       url.Values{"new": {"password"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			want: exposed.PasswordRejection{
+				Error:     "pwned_password",
+				Message:   exposed.DefaultFeedback.Message(10434004),
+				Count:     10434004,
+				Threshold: 1000,
+			},
+		},
+		{
+			name:       "not found",
+			client:     client,
+			form:       url.Values{"password": {"correct horse battery staple"}},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "no password",
+			client:     client,
+			form:       url.Values{"user": {"bob"}},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "blocked",
+			client:     blocking,
+			policy:     exposed.PasswordPolicy{Threshold: 1000},
+			form:       url.Values{"password": {"acme"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			want: exposed.PasswordRejection{
+				Error:   "blocked_password",
+				Message: "This password is not allowed; choose another.",
+			},
+		},
+		{
+			name:       "not blocked",
+			client:     blocking,
+			form:       url.Values{"password": {"correct horse battery staple"}},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "fail closed",
+			client:     failing,
+			form:       url.Values{"password": {"password"}},
+			wantStatus: http.StatusServiceUnavailable,
+			want: exposed.PasswordRejection{
+				Error:   "check_failed",
+				Message: "The password could not be checked; try again later.",
+			},
+		},
+		{
+			name:       "fail open",
+			client:     failing,
+			policy:     exposed.PasswordPolicy{FailOpen: true},
+			form:       url.Values{"password": {"password"}},
+			wantStatus: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			h := exposed.RejectPwnedPassword(tc.client, tc.policy, next)
+
+			req := httptest.NewRequest(http.MethodPost, "/signup", strings.NewReader(tc.form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantStatus {
+				t.Fatalf("status = %d, expected %d", rec.Code, tc.wantStatus)
+			}
+			if tc.wantStatus == http.StatusCreated {
+				return
+			}
+
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("Content-Type = %q, expected application/json", ct)
+			}
+			var got exposed.PasswordRejection
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON body %q: %v", rec.Body.String(), err)
+			}
+			if got != tc.want {
+				t.Errorf("body = %+v, expected %+v", got, tc.want)
+			}
+			if tc.want.Count == 0 && strings.Contains(rec.Body.String(), `"count"`) {
+				t.Errorf("body = %q, expected no count", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import "math/rand"
+
+// MirrorStrategy selects the order in which the base URL and mirrors are
+// tried for each range, see WithMirrorStrategy.
+type MirrorStrategy int
+
+const (
+	MirrorFailover   MirrorStrategy = iota // base URL first, then the mirrors in order
+	MirrorRoundRobin                       // each range starts with the next in turn
+	MirrorRandom                           // each range starts with a random one
+)
+
+// WithMirrors adds fallback base URLs for the range API, e.g., mirrors in
+// other regions, tried in order after the base URL given to NewPwnedClient
+// if it fails, i.e., if a request still fails after its retries or returns
+// a non-OK status. Each mirror has the same retries, and every attempt
+// counts against the rate limit and request budget. The base URL that
+// served a range is reported in Result.Mirror, and if every mirror fails,
+// the joined errors are returned. Ping and Warmup only use the base URL.
+func WithMirrors(mirrors []string) Option {
+	return func(c *PwnedClient) {
+		c.mirrors = append([]string(nil), mirrors...)
+	}
+}
+
+// WithMirrorStrategy sets how ranges are spread across the base URL and the
+// mirrors set with WithMirrors. The default, MirrorFailover, sends every
+// range to the base URL and only uses the mirrors if it fails. The other
+// strategies spread the load, e.g., across several internal mirrors, by
+// starting each range at a different one, and fail over to the rest in
+// order. Either way, a range is only fetched if it is not cached, and the
+// cache, rate limit, and request budget are shared by all mirrors.
+func WithMirrorStrategy(s MirrorStrategy) Option {
+	return func(c *PwnedClient) {
+		c.mirrorStrategy = s
+	}
+}
+
+// baseURLs returns the base URL and the mirrors in the order to try them for
+// the next range.
+func (c *PwnedClient) baseURLs() []string {
+	urls := append([]string{c.baseURL}, c.mirrors...)
+
+	var start int
+	switch c.mirrorStrategy {
+	case MirrorRoundRobin:
+		start = int((c.mirrorNext.Add(1) - 1) % uint64(len(urls)))
+	case MirrorRandom:
+		start = rand.Intn(len(urls))
+	}
+	return append(urls[start:], urls[:start]...)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestWithMirrors(t *testing.T) {
+	var failing, working atomic.Int32
+	primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		failing.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer primary.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		working.Add(1)
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer mirror.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, primary.URL,
+		exposed.WithMirrors([]string{down.URL, mirror.URL}),
+		exposed.WithCache(10, time.Hour))
+
+	result, err := c.CheckPwnedWithResult("password", "password", "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedWithResult() error = %v", err)
+	}
+	if result.Count != 10434004 || result.Mirror != mirror.URL {
+		t.Errorf("result = %+v, expected count 10434004 from %s", result, mirror.URL)
+	}
+	if failing.Load() != 1 || working.Load() != 1 {
+		t.Errorf("got %d and %d requests, expected 1 to each", failing.Load(), working.Load())
+	}
+
+	// a cached range reports the mirror that served it
+	result, err = c.CheckPwnedWithResult("password", "password", "sha1")
+	if err != nil || result.Mirror != mirror.URL {
+		t.Errorf("cached result = %+v, %v, expected from %s", result, err, mirror.URL)
+	}
+	if failing.Load() != 1 || working.Load() != 1 {
+		t.Errorf("got %d and %d requests, expected the cache to be used", failing.Load(), working.Load())
+	}
+
+	// batches fail over too
+	c = exposed.NewPwnedClient(&http.Client{}, primary.URL, exposed.WithMirrors([]string{mirror.URL}))
+	results, err := c.CheckPwnedPasswords(context.Background(), []string{"password"}, "sha1")
+	if err != nil || results[0].Count != 10434004 || results[0].Mirror != mirror.URL {
+		t.Errorf("CheckPwnedPasswords() = %+v, %v, expected count 10434004 from %s", results, err, mirror.URL)
+	}
+
+	// every mirror failing is an error
+	c = exposed.NewPwnedClient(&http.Client{}, primary.URL, exposed.WithMirrors([]string{down.URL}))
+	if _, err := c.CheckPwnedPassword("password", "sha1"); err == nil {
+		t.Error("CheckPwnedPassword() expected error when every mirror fails")
+	}
+}
+
+func TestWithMirrorsCanceled(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithMirrors([]string{server.URL}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.CheckPwnedPasswordContext(ctx, "password", "sha1"); err == nil {
+		t.Fatal("CheckPwnedPasswordContext() expected error")
+	}
+	// a done ctx is not retried on the mirror
+	if got := requests.Load(); got != 1 {
+		t.Errorf("got %d requests, expected 1", got)
+	}
+}
+
+func TestWithMirrorStrategy(t *testing.T) {
+	newServer := func(requests *atomic.Int32) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+		}))
+	}
+	hashes := []string{
+		"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8",
+		"0000000000000000000000000000000000000000",
+		"1111111111111111111111111111111111111111",
+		"2222222222222222222222222222222222222222",
+	}
+
+	tests := []struct {
+		name     string
+		strategy exposed.MirrorStrategy
+		check    func(t *testing.T, a, b int32)
+	}{
+		{name: "failover", strategy: exposed.MirrorFailover, check: func(t *testing.T, a, b int32) {
+			if a != 4 || b != 0 {
+				t.Errorf("got %d and %d requests, expected 4 and 0", a, b)
+			}
+		}},
+		{name: "round robin", strategy: exposed.MirrorRoundRobin, check: func(t *testing.T, a, b int32) {
+			if a != 2 || b != 2 {
+				t.Errorf("got %d and %d requests, expected 2 to each", a, b)
+			}
+		}},
+		{name: "random", strategy: exposed.MirrorRandom, check: func(t *testing.T, a, b int32) {
+			if a+b != 4 {
+				t.Errorf("got %d requests, expected 4", a+b)
+			}
+		}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var a, b atomic.Int32
+			serverA, serverB := newServer(&a), newServer(&b)
+			defer serverA.Close()
+			defer serverB.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, serverA.URL,
+				exposed.WithMirrors([]string{serverB.URL}),
+				exposed.WithMirrorStrategy(tc.strategy),
+				exposed.WithCache(10, time.Hour))
+
+			for _, hash := range hashes {
+				if _, err := c.CheckPwnedHash(hash, "sha1"); err != nil {
+					t.Fatalf("CheckPwnedHash(%q) error = %v", hash, err)
+				}
+			}
+			tc.check(t, a.Load(), b.Load())
+
+			// cached ranges are not fetched again from any mirror
+			for _, hash := range hashes {
+				if _, err := c.CheckPwnedHash(hash, "sha1"); err != nil {
+					t.Fatalf("CheckPwnedHash(%q) error = %v", hash, err)
+				}
+			}
+			if a.Load()+b.Load() != 4 {
+				t.Errorf("got %d requests, expected the cache to be used", a.Load()+b.Load())
+			}
+		})
+	}
+
+	// round robin still fails over when the chosen mirror fails
+	var working atomic.Int32
+	server := newServer(&working)
+	defer server.Close()
+	down := httptest.NewServer(http.NotFoundHandler())
+	down.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithMirrors([]string{down.URL}),
+		exposed.WithMirrorStrategy(exposed.MirrorRoundRobin))
+	for _, hash := range hashes {
+		result, err := c.CheckPwnedHashWithResult(hash, "sha1")
+		if err != nil || result.Mirror != server.URL {
+			t.Errorf("CheckPwnedHashWithResult(%q) = %+v, %v, expected from %s", hash, result, err, server.URL)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import "golang.org/x/text/unicode/norm"
+
+// Normalization selects the Unicode normalization applied to passwords
+// before hashing.
+//
+// The Pwned Passwords corpus contains hashes of passwords as they appeared in
+// breaches, which were not normalized consistently. A password that is
+// visually identical but uses a different composition, e.g., "é" as one code
+// point versus "e" followed by a combining accent, hashes differently. This
+// matters for NTLM in particular, since the UTF-16LE encoding hashed by NTLM
+// preserves the exact code points. Normalizing helps when the system that
+// stores the password normalizes before hashing, but it can also hide a match
+// for the exact bytes the user typed, so it is off by default. NFKC also
+// folds compatibility characters, e.g., "ﬁ" to "fi", which changes the
+// password more aggressively than NFC.
+type Normalization int
+
+const (
+	NormalizeNone Normalization = iota // check passwords as given
+	NormalizeNFC                       // canonical composition
+	NormalizeNFKC                      // compatibility composition
+)
+
+// WithNormalization sets the Unicode normalization applied to passwords
+// before hashing. The default is NormalizeNone. Hash lookups are not
+// affected.
+func WithNormalization(n Normalization) Option {
+	return func(c *PwnedClient) {
+		c.normalization = n
+	}
+}
+
+// normalize applies the client's normalization to password.
+func (c *PwnedClient) normalize(password string) string {
+	switch c.normalization {
+	case NormalizeNFC:
+		return norm.NFC.String(password)
+	case NormalizeNFKC:
+		return norm.NFKC.String(password)
+	default:
+		return password
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestWithNormalization(t *testing.T) {
+	const (
+		composed   = "caf\u00e9"  // é as a single code point
+		decomposed = "cafe\u0301" // e followed by a combining acute accent
+		ligature   = "\ufb01le"   // "file" with the fi ligature
+	)
+
+	tests := []struct {
+		name          string
+		normalization exposed.Normalization
+		a, b          string
+		wantSame      bool
+	}{
+		{name: "none", normalization: exposed.NormalizeNone, a: composed, b: decomposed, wantSame: false},
+		{name: "NFC", normalization: exposed.NormalizeNFC, a: composed, b: decomposed, wantSame: true},
+		{name: "NFC keeps ligature", normalization: exposed.NormalizeNFC, a: ligature, b: "file", wantSame: false},
+		{name: "NFKC folds ligature", normalization: exposed.NormalizeNFKC, a: ligature, b: "file", wantSame: true},
+	}
+
+	for _, tc := range tests {
+		for _, mode := range exposed.ValidHashes {
+			t.Run(tc.name+" "+mode, func(t *testing.T) {
+				var prefixes []string
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					prefixes = append(prefixes, r.URL.Path)
+				}))
+				defer server.Close()
+
+				c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithNormalization(tc.normalization))
+				for _, p := range []string{tc.a, tc.b} {
+					if _, err := c.CheckPwnedPassword(p, mode); err != nil {
+						t.Fatalf("CheckPwnedPassword(%q) error = %v", p, err)
+					}
+				}
+
+				if same := prefixes[0] == prefixes[1]; same != tc.wantSame {
+					t.Errorf("prefixes %v same = %v, expected %v", prefixes, same, tc.wantSame)
+				}
+			})
+		}
+	}
+}
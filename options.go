@@ -0,0 +1,181 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultPaddingHeader and DefaultPaddingValue form the header sent to
+// request padded responses, which hide the number of matching suffixes.
+const (
+	DefaultPaddingHeader = "Add-Padding"
+	DefaultPaddingValue  = "true"
+)
+
+// ErrInvalidHeader is returned by requests if an option set an invalid
+// header name or value.
+var ErrInvalidHeader = errors.New("invalid header")
+
+// Option configures a PwnedClient.
+type Option func(*PwnedClient)
+
+// WithMethod sets the HTTP method used for range requests. The Pwned
+// Passwords API only supports GET, which is the default, but some caching
+// mirrors and custom backends expect another method such as POST. The
+// backend must return the range in the response body, so HEAD, whose
+// responses have no body, is an error returned by every request, since it
+// would report every hash as not found.
+func WithMethod(method string) Option {
+	return func(c *PwnedClient) {
+		if strings.EqualFold(method, http.MethodHead) {
+			c.setConfigErr(fmt.Errorf("invalid method %q: range responses need a body", method))
+			return
+		}
+		c.method = method
+	}
+}
+
+// requestMethod returns the HTTP method to use for range requests.
+func (c *PwnedClient) requestMethod() string {
+	if c.method == "" {
+		return http.MethodGet
+	}
+	return c.method
+}
+
+// WithAccept sets the Accept header sent with range requests, e.g.,
+// "application/json" for mirrors that can return a JSON object mapping
+// suffixes to counts. Regardless of the Accept header, the response is
+// parsed according to its Content-Type.
+func WithAccept(accept string) Option {
+	return func(c *PwnedClient) {
+		c.accept = accept
+	}
+}
+
+// WithTimeout sets the time limit for each request made by the client,
+// including reading the response body. The HTTP client passed to
+// NewPwnedClient is copied rather than modified.
+func WithTimeout(d time.Duration) Option {
+	return func(c *PwnedClient) {
+		client := *c.httpClient
+		client.Timeout = d
+		c.httpClient = &client
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with requests so operators
+// can identify the source of traffic. If empty, DefaultUserAgent is used.
+func WithUserAgent(userAgent string) Option {
+	return func(c *PwnedClient) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithResponseSizeLimit sets the maximum size in bytes of a range response.
+// Reading more than n bytes fails with ErrResponseTooLarge, which protects
+// against a broken or malicious server returning an enormous body. If n is
+// not positive, DefaultResponseSizeLimit is used.
+func WithResponseSizeLimit(n int64) Option {
+	return func(c *PwnedClient) {
+		c.maxResponseSize = n
+	}
+}
+
+// WithNotFoundAsEmpty sets whether a 404 Not Found response to a range
+// request is treated as an empty range, so every hash with the prefix is
+// reported as not found. Some mirrors return 404 for prefixes without data.
+// The default is false, which treats 404 as an error, as is appropriate for
+// the Pwned Passwords API, which serves every prefix.
+func WithNotFoundAsEmpty(enabled bool) Option {
+	return func(c *PwnedClient) {
+		c.notFoundAsEmpty = enabled
+	}
+}
+
+// WithPaddingHeader sets the name and value of the header sent to request
+// padded responses, for mirrors that expect a nonstandard header. The
+// default is "Add-Padding: true". The name must be a valid header field name
+// and the value must be non-empty without control characters, otherwise
+// every request fails with ErrInvalidHeader.
+func WithPaddingHeader(name, value string) Option {
+	return func(c *PwnedClient) {
+		if !validHeaderName(name) {
+			c.setConfigErr(fmt.Errorf("%w: name %q", ErrInvalidHeader, name))
+			return
+		}
+		if value == "" || strings.IndexFunc(value, isCTL) >= 0 {
+			c.setConfigErr(fmt.Errorf("%w: value %q for %s", ErrInvalidHeader, value, name))
+			return
+		}
+		c.paddingHeader = http.CanonicalHeaderKey(name)
+		c.paddingValue = value
+	}
+}
+
+// padding returns the name and value of the padding header.
+func (c *PwnedClient) padding() (name, value string) {
+	if c.paddingHeader == "" {
+		return DefaultPaddingHeader, DefaultPaddingValue
+	}
+	return c.paddingHeader, c.paddingValue
+}
+
+// validHeaderName reports whether name is a non-empty HTTP token.
+func validHeaderName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r > 0x7e || !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' ||
+			r >= '0' && r <= '9' || strings.ContainsRune("!#$%&'*+-.^_`|~", r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// isCTL reports whether r is a control character other than tab.
+func isCTL(r rune) bool {
+	return r != '\t' && (r < 0x20 || r == 0x7f)
+}
+
+// DefaultPrefixLength is the number of hex characters of a hash sent to the
+// range API if not set with WithPrefixLength.
+const DefaultPrefixLength = 5
+
+// WithPrefixLength sets the number of hex characters of a hash sent to the
+// range API as the prefix, the rest being the suffix matched in the
+// response. The Pwned Passwords API uses DefaultPrefixLength, so this is
+// only useful for experimental backends or alternate datasets that split
+// hashes differently. The length must be positive and shorter than the
+// hashes of every mode, otherwise every request returns an error.
+func WithPrefixLength(n int) Option {
+	return func(c *PwnedClient) {
+		// the shortest hash bounds the prefix of every mode
+		mode, length := "", 0
+		for m, l := range hashLengths {
+			if length == 0 || l < length {
+				mode, length = m, l
+			}
+		}
+		if n <= 0 || n >= length {
+			c.setConfigErr(fmt.Errorf("invalid prefix length %d: must be between 1 and %d for %s hashes", n, length-1, mode))
+			return
+		}
+		c.prefixLength = n
+	}
+}
+
+// prefixLen returns the number of hex characters in a range prefix.
+func (c *PwnedClient) prefixLen() int {
+	if c.prefixLength <= 0 {
+		return DefaultPrefixLength
+	}
+	return c.prefixLength
+}
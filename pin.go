@@ -0,0 +1,68 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCertificatePin is returned when the server's certificate does not match
+// the fingerprint pinned with WithPinnedCert.
+var ErrCertificatePin = errors.New("certificate does not match pinned fingerprint")
+
+// WithPinnedCert only allows TLS connections to servers whose leaf
+// certificate has the SHA-256 fingerprint, given as 64 hex characters,
+// optionally separated by colons, e.g., as printed by
+// "openssl x509 -noout -fingerprint -sha256". This adds assurance for a
+// known endpoint, such as a self-hosted mirror. The certificate must still
+// be trusted, by the system roots or the RootCAs of the client's transport.
+// Connections to any other certificate fail with an error wrapping
+// ErrCertificatePin.
+//
+// An invalid fingerprint, or a client whose transport cannot be configured
+// because it is shared or is not an *http.Transport, is an error returned by
+// every request, so pinning is never silently skipped.
+func WithPinnedCert(fingerprint string) Option {
+	return func(c *PwnedClient) {
+		pin, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+		if err != nil || len(pin) != sha256.Size {
+			c.setConfigErr(fmt.Errorf("invalid pinned certificate fingerprint %q: must be %d hex bytes", fingerprint, sha256.Size))
+			return
+		}
+		c.transport.pinnedCert = pin
+	}
+}
+
+// pinnedTLSConfig returns a clone of base, which may be nil, that verifies
+// the leaf certificate of each connection, including resumed ones, has the
+// SHA-256 fingerprint pin.
+func pinnedTLSConfig(base *tls.Config, pin []byte) *tls.Config {
+	var cfg *tls.Config
+	if base != nil {
+		cfg = base.Clone()
+	} else {
+		cfg = &tls.Config{}
+	}
+
+	verify := cfg.VerifyConnection
+	cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return fmt.Errorf("%w: no certificate", ErrCertificatePin)
+		}
+		sum := sha256.Sum256(cs.PeerCertificates[0].Raw)
+		if !bytes.Equal(sum[:], pin) {
+			return fmt.Errorf("%w: got %s", ErrCertificatePin, strings.ToUpper(hex.EncodeToString(sum[:])))
+		}
+		if verify != nil {
+			return verify(cs)
+		}
+		return nil
+	}
+	return cfg
+}
@@ -0,0 +1,300 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// openRange returns the body and content type of the range response for
+// prefix and mode, from the cache if enabled, along with the base URL that
+// served it. Each of the base URL and mirrors is tried in order until one
+// succeeds, see WithMirrors. The caller must close the body.
+func (c *PwnedClient) openRange(ctx context.Context, prefix, mode string) (io.ReadCloser, string, string, error) {
+	key := cacheKey(prefix, mode)
+	if c.cache != nil {
+		if entry, ok := c.cache.get(key, c.now()); ok {
+			c.logDebug(ctx, "range served from cache", "prefix", prefix, "mode", mode)
+			return io.NopCloser(bytes.NewReader(entry.body)), entry.contentType, entry.mirror, nil
+		}
+	}
+
+	if len(c.mirrors) == 0 {
+		body, contentType, err := c.fetchRange(ctx, c.baseURL, prefix, mode)
+		return body, contentType, c.baseURL, err
+	}
+
+	var errs []error
+	for _, baseURL := range c.baseURLs() {
+		body, contentType, err := c.fetchRange(ctx, baseURL, prefix, mode)
+		if err == nil {
+			return body, contentType, baseURL, nil
+		}
+		// another mirror cannot help if the caller gave up, the budget is
+		// used up, or the client is misconfigured
+		if ctx.Err() != nil || errors.Is(err, ErrBudgetExceeded) || c.configErr != nil {
+			return nil, "", "", err
+		}
+		c.logDebug(ctx, "mirror failed", "mirror", baseURL, "prefix", prefix, "mode", mode, "error", err)
+		errs = append(errs, err)
+	}
+	return nil, "", "", fmt.Errorf("all mirrors failed: %w", errors.Join(errs...))
+}
+
+// fetchRange requests the range for prefix and mode from baseURL, with
+// retries, and adds the response to the cache if enabled. It returns the
+// body and content type of the response. The caller must close the body.
+func (c *PwnedClient) fetchRange(ctx context.Context, baseURL, prefix, mode string) (io.ReadCloser, string, error) {
+	key := cacheKey(prefix, mode)
+	reqURL, err := buildURL(baseURL, prefix, mode)
+	if err != nil {
+		return nil, "", err
+	}
+
+	req, err := c.newRangeRequest(ctx, reqURL)
+	if err != nil {
+		return nil, "", err
+	}
+
+	start := c.now()
+	resp, err := c.do(req)
+	if err != nil {
+		c.logDebug(ctx, "range request failed", "prefix", prefix, "mode", mode, "error", err)
+		return nil, "", err
+	}
+	c.logDebug(ctx, "range request", "prefix", prefix, "mode", mode,
+		"status", resp.StatusCode, "duration", c.now().Sub(start))
+
+	if resp.StatusCode == http.StatusNotFound && c.notFoundAsEmpty {
+		// drain body to allow reuse of the connection
+		_, _ = io.Copy(io.Discard, c.responseBody(resp))
+		resp.Body.Close()
+		if c.cache != nil {
+			c.cache.add(cacheEntry{key: key, mirror: baseURL}, c.now())
+		}
+		return io.NopCloser(bytes.NewReader(nil)), "", nil
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("received non-OK HTTP status for %q: %d", reqURL, resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if c.cache == nil {
+		return &bodyReader{Reader: c.responseBody(resp), Closer: resp.Body}, contentType, nil
+	}
+
+	defer resp.Body.Close()
+	body, err := io.ReadAll(c.responseBody(resp))
+	if err != nil {
+		return nil, "", err
+	}
+	c.cache.add(cacheEntry{key: key, body: body, contentType: contentType, mirror: baseURL}, c.now())
+
+	return io.NopCloser(bytes.NewReader(body)), contentType, nil
+}
+
+// bodyReader reads from a wrapped response body and closes the original.
+type bodyReader struct {
+	io.Reader
+	io.Closer
+}
+
+// FetchRange returns the body of the range response for prefix, five hex
+// characters unless set with WithPrefixLength, and mode, exactly as returned
+// by the API, for callers that parse the range themselves. A non-OK status
+// is an error. The body is limited by WithResponseSizeLimit and served from
+// the cache if enabled. The caller must close the body.
+func (c *PwnedClient) FetchRange(ctx context.Context, prefix, mode string) (io.ReadCloser, error) {
+	prefix = strings.ToUpper(prefix)
+	if err := c.validatePrefix(prefix); err != nil {
+		return nil, err
+	}
+	if _, ok := hashLengths[mode]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+
+	body, _, _, err := c.openRange(ctx, prefix, mode)
+	return body, err
+}
+
+// DownloadRange writes the range response for prefix, as for FetchRange, and
+// mode to w, exactly as returned by the API, e.g., to populate an offline
+// store. See BuildRangeIndex for the store layout.
+func (c *PwnedClient) DownloadRange(ctx context.Context, prefix, mode string, w io.Writer) error {
+	body, err := c.FetchRange(ctx, prefix, mode)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	_, err = io.Copy(w, body)
+	return err
+}
+
+// FetchRanges fetches the range for each of prefixes, as for FetchRange, and
+// returns the counts of each range by uppercase suffix, keyed by uppercase
+// prefix, for callers that have already grouped their hashes. Each distinct
+// prefix is fetched once, up to WithConcurrency at once, using the client's
+// cache, rate limit, and retries. Padding entries with a count of zero are
+// omitted. If any prefix is invalid, nothing is fetched. If any fetch fails,
+// the ranges fetched successfully are returned along with the joined errors.
+func (c *PwnedClient) FetchRanges(ctx context.Context, prefixes []string, mode string) (map[string]map[string]int, error) {
+	if _, ok := hashLengths[mode]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidMode, mode)
+	}
+
+	seen := make(map[string]bool, len(prefixes))
+	jobs := make(chan string, len(prefixes))
+	for _, prefix := range prefixes {
+		prefix = strings.ToUpper(prefix)
+		if err := c.validatePrefix(prefix); err != nil {
+			return nil, err
+		}
+		if !seen[prefix] {
+			seen[prefix] = true
+			jobs <- prefix
+		}
+	}
+	close(jobs)
+
+	var (
+		mu     sync.Mutex
+		ranges = make(map[string]map[string]int, len(seen))
+		errs   []error
+		wg     sync.WaitGroup
+	)
+	for i := c.workers(len(seen)); i > 0; i-- {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for prefix := range jobs {
+				counts, err := c.fetchCounts(ctx, prefix, mode)
+
+				mu.Lock()
+				if err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", prefix, err))
+				} else {
+					ranges[prefix] = counts
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	return ranges, errors.Join(errs...)
+}
+
+// fetchCounts fetches the range for prefix and returns its counts by
+// suffix.
+func (c *PwnedClient) fetchCounts(ctx context.Context, prefix, mode string) (map[string]int, error) {
+	body, contentType, _, err := c.openRange(ctx, prefix, mode)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	return parseRange(body, contentType)
+}
+
+// parseRange returns the counts by uppercase suffix of a range response,
+// decoded as JSON if contentType is application/json, otherwise parsed as
+// colon-delimited lines. Entries with a count of zero are padding and are
+// omitted. An empty or whitespace-only body has no entries.
+func parseRange(body io.Reader, contentType string) (map[string]int, error) {
+	counts := make(map[string]int)
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil && mediaType == "application/json" {
+		var entries map[string]int
+		if err := json.NewDecoder(body).Decode(&entries); err != nil {
+			// an empty or whitespace-only body has no entries
+			if err == io.EOF {
+				return counts, nil
+			}
+			return nil, fmt.Errorf("invalid JSON range: %w", err)
+		}
+		for suffix, count := range entries {
+			if count > 0 {
+				counts[strings.ToUpper(suffix)] = count
+			}
+		}
+		return counts, nil
+	}
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		suffix, _, _ := strings.Cut(line, ":")
+		count, err := extractCount(line)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range line %q: %w", line, err)
+		}
+		if count > 0 {
+			counts[strings.ToUpper(suffix)] = count
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// validatePrefix checks that prefix is an uppercase hex string of the
+// client's prefix length.
+func (c *PwnedClient) validatePrefix(prefix string) error {
+	if n := c.prefixLen(); len(prefix) != n || strings.IndexFunc(prefix, notUpperHex) >= 0 {
+		return fmt.Errorf("%w: prefix must be %d hex characters: %q", ErrInvalidHash, n, prefix)
+	}
+	return nil
+}
+
+// zeroPrefix returns the all-zero prefix, a range that always exists,
+// requested by Ping and Warmup.
+func (c *PwnedClient) zeroPrefix() string {
+	return strings.Repeat("0", c.prefixLen())
+}
+
+// Ping checks that the range API is reachable by requesting a known prefix
+// and verifying a 200 OK response, e.g., before starting a large job or as a
+// readiness probe. The body is not parsed and the cache is bypassed, but the
+// client's transport, headers, API key, retries, and rate limit are used.
+func (c *PwnedClient) Ping(ctx context.Context) error {
+	reqURL, err := buildURL(c.baseURL, c.zeroPrefix(), "sha1")
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRangeRequest(ctx, reqURL)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// drain body to allow reuse of the connection
+	_, _ = io.Copy(io.Discard, c.responseBody(resp))
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("received non-OK HTTP status for %q: %d", reqURL, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+// DefaultCorpusSize is the approximate number of passwords in the Pwned
+// Passwords dataset as of 2024, used by EstimateRarity when no size is given.
+const DefaultCorpusSize = 936_000_000
+
+// rarityBuckets are the labels for counts whose share of the corpus is at
+// least minShare, from most to least common.
+var rarityBuckets = []struct {
+	minShare float64
+	label    string
+}{
+	{1e-5, "extremely common"},
+	{1e-7, "very common"},
+	{1e-8, "common"},
+	{0, "rare"},
+}
+
+// RarityEstimate is a rough estimate of how common a password is, intended
+// for friendlier messages than a raw count. It is only an estimate since the
+// distribution of counts is not published and the corpus grows over time.
+type RarityEstimate struct {
+	Share float64 // count as a fraction of the corpus size
+	Label string  // e.g., "very common", or "not found" for a zero count
+}
+
+// EstimateRarity estimates how common a password with count is relative to a
+// corpus of corpusSize passwords. If corpusSize is not positive,
+// DefaultCorpusSize is used. With the default size, a count of roughly
+// 10,000 or more is "extremely common", roughly 100 or more is "very
+// common", 10 or more is "common", and anything less is "rare".
+func EstimateRarity(count int, corpusSize int64) RarityEstimate {
+	if count <= 0 {
+		return RarityEstimate{Label: "not found"}
+	}
+	if corpusSize <= 0 {
+		corpusSize = DefaultCorpusSize
+	}
+
+	share := float64(count) / float64(corpusSize)
+	for _, b := range rarityBuckets {
+		if share >= b.minShare {
+			return RarityEstimate{Share: share, Label: b.label}
+		}
+	}
+	return RarityEstimate{Share: share, Label: rarityBuckets[len(rarityBuckets)-1].label}
+}
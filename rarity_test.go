@@ -0,0 +1,38 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestEstimateRarity(t *testing.T) {
+	tests := []struct {
+		count      int
+		corpusSize int64
+		want       string
+	}{
+		{0, 0, "not found"},
+		{1, 0, "rare"},
+		{9, 0, "rare"},
+		{10, 0, "common"},
+		{100, 0, "very common"},
+		{10434004, 0, "extremely common"},
+		{10, 1000, "extremely common"},
+		{10, 1_000_000_000_000, "rare"},
+	}
+
+	for _, tc := range tests {
+		got := exposed.EstimateRarity(tc.count, tc.corpusSize)
+		if got.Label != tc.want {
+			t.Errorf("EstimateRarity(%d, %d) = %+v, expected %q", tc.count, tc.corpusSize, got, tc.want)
+		}
+	}
+
+	got := exposed.EstimateRarity(936, 0)
+	if got.Share != 1e-6 {
+		t.Errorf("EstimateRarity(936, 0).Share = %v, expected %v", got.Share, 1e-6)
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import "golang.org/x/time/rate"
+
+// WithRateLimit limits the client to rps requests per second on average with
+// bursts of up to burst requests, using a token bucket shared by all
+// goroutines using the client. Each attempt, including retries, takes a
+// token. If rps is not positive, requests are not limited.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(c *PwnedClient) {
+		if rps <= 0 {
+			c.limiter = nil
+			return
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		c.limiter = rate.NewLimiter(rate.Limit(rps), burst)
+	}
+}
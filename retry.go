@@ -0,0 +1,180 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// DefaultRetryableStatuses are the HTTP status codes retried when a
+// RetryPolicy does not specify its own set.
+var DefaultRetryableStatuses = map[int]bool{
+	http.StatusRequestTimeout:     true,
+	http.StatusTooManyRequests:    true,
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// RetryPolicy controls how failed range requests are retried. The zero
+// value disables retries.
+//
+// A request is retried if it fails with a transport error or if the
+// response status is in RetryableStatuses. If a retryable response includes
+// a Retry-After header, the delay it specifies is used instead of the
+// computed backoff, limited by MaxDelay. Retry-After is ignored for statuses
+// that are not retryable, so those responses are returned immediately.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt.
+	MaxRetries int
+
+	// BaseDelay is the delay before the first retry. The delay doubles
+	// with each subsequent retry.
+	BaseDelay time.Duration
+
+	// MaxDelay limits the delay between attempts. Zero means no limit.
+	MaxDelay time.Duration
+
+	// RetryableStatuses is the set of HTTP status codes to retry. If nil,
+	// DefaultRetryableStatuses is used.
+	RetryableStatuses map[int]bool
+}
+
+// WithRetryPolicy sets the policy used to retry failed range requests.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *PwnedClient) {
+		c.retryPolicy = p
+	}
+}
+
+// retryable reports whether status should be retried.
+func (p RetryPolicy) retryable(status int) bool {
+	statuses := p.RetryableStatuses
+	if statuses == nil {
+		statuses = DefaultRetryableStatuses
+	}
+	return statuses[status]
+}
+
+// limit caps d at MaxDelay, if set.
+func (p RetryPolicy) limit(d time.Duration) time.Duration {
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return d
+}
+
+// backoff returns a Backoff that doubles from BaseDelay up to MaxDelay.
+func (p RetryPolicy) backoff() Backoff {
+	return Backoff{Base: p.BaseDelay, Max: p.MaxDelay, Multiplier: 2}
+}
+
+// retryAfter returns the delay requested by the Retry-After header of resp,
+// which may be either a number of seconds or an HTTP date relative to now.
+func retryAfter(resp *http.Response, now time.Time) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
+
+// do sends req, retrying according to the client's RetryPolicy and waiting
+// for the rate limiter, if any, before each attempt. Each attempt uses one
+// request from the budget, if any. Waiting stops early if the request's
+// context is done. A request with a body is resent with a fresh body from
+// req.GetBody, and is not retried if it has no GetBody.
+func (c *PwnedClient) do(req *http.Request) (*http.Response, error) {
+	p := c.retryPolicy
+	b := p.backoff()
+	hasBody := req.Body != nil && req.Body != http.NoBody
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && hasBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(req.Context())
+			req.Body = body
+		}
+
+		if c.budget != nil {
+			if err := c.budget.take(); err != nil {
+				return nil, err
+			}
+		}
+
+		if c.limiter != nil {
+			start := c.now()
+			err := c.limiter.Wait(req.Context())
+			if c.stats != nil {
+				c.stats.wait.Add(int64(c.now().Sub(start)))
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if c.stats != nil {
+			c.stats.requests.Add(1)
+			if attempt > 0 {
+				c.stats.retries.Add(1)
+			}
+		}
+
+		start := c.now()
+		resp, err := c.httpClient.Do(req)
+		if c.stats != nil {
+			c.stats.latency.record(c.now().Sub(start))
+		}
+		// the body of the first attempt was consumed and cannot be sent
+		// again without GetBody
+		if attempt >= p.MaxRetries || (hasBody && req.GetBody == nil) {
+			return resp, err
+		}
+
+		delay := b.Next()
+		if err == nil {
+			if !p.retryable(resp.StatusCode) {
+				return resp, nil
+			}
+			if d, ok := retryAfter(resp, c.now()); ok {
+				delay = p.limit(d)
+			}
+
+			// drain body to allow reuse of the connection
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		if err != nil {
+			c.logDebug(req.Context(), "retrying request", "url", req.URL.String(),
+				"attempt", attempt+1, "delay", delay, "error", err)
+		} else {
+			c.logDebug(req.Context(), "retrying request", "url", req.URL.String(),
+				"attempt", attempt+1, "delay", delay, "status", resp.StatusCode)
+		}
+
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-c.after(delay):
+		}
+	}
+}
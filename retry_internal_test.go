@@ -0,0 +1,62 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDoRetryBody(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(b))
+		if len(bodies) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	c := NewPwnedClient(&http.Client{}, server.URL,
+		WithRetryPolicy(RetryPolicy{MaxRetries: 2}))
+
+	tests := []struct {
+		name       string
+		getBody    bool
+		wantBodies []string
+		wantStatus int
+	}{
+		// the body is rebuilt for the retry
+		{"GetBody", true, []string{"prefix=5BAA6", "prefix=5BAA6"}, http.StatusOK},
+		// without GetBody, the consumed body cannot be resent
+		{"no GetBody", false, []string{"prefix=5BAA6"}, http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range tests {
+		bodies = nil
+		req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("prefix=5BAA6"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !tc.getBody {
+			req.GetBody = nil
+		}
+
+		resp, err := c.do(req)
+		if err != nil {
+			t.Fatalf("%s: do() error = %v", tc.name, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.wantStatus {
+			t.Errorf("%s: status = %d, expected %d", tc.name, resp.StatusCode, tc.wantStatus)
+		}
+		if strings.Join(bodies, "|") != strings.Join(tc.wantBodies, "|") {
+			t.Errorf("%s: bodies = %q, expected %q", tc.name, bodies, tc.wantBodies)
+		}
+	}
+}
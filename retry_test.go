@@ -0,0 +1,174 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestWithRetryPolicy(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       exposed.RetryPolicy
+		statuses     []int // statuses returned before a 200
+		retryAfter   string
+		wantRequests int
+		wantErr      bool
+	}{
+		{
+			name:         "no retries by default",
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name:         "default statuses retried",
+			policy:       exposed.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond},
+			statuses:     []int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			wantRequests: 3,
+		},
+		{
+			name:         "retries exhausted",
+			policy:       exposed.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond},
+			statuses:     []int{http.StatusBadGateway, http.StatusBadGateway},
+			wantRequests: 2,
+			wantErr:      true,
+		},
+		{
+			name: "status not in configured set",
+			policy: exposed.RetryPolicy{
+				MaxRetries:        2,
+				BaseDelay:         time.Millisecond,
+				RetryableStatuses: map[int]bool{http.StatusServiceUnavailable: true},
+			},
+			statuses:     []int{http.StatusTooManyRequests},
+			retryAfter:   "0",
+			wantRequests: 1,
+			wantErr:      true,
+		},
+		{
+			name: "status in configured set",
+			policy: exposed.RetryPolicy{
+				MaxRetries:        2,
+				BaseDelay:         time.Millisecond,
+				RetryableStatuses: map[int]bool{http.StatusServiceUnavailable: true},
+			},
+			statuses:     []int{http.StatusServiceUnavailable},
+			wantRequests: 2,
+		},
+		{
+			name:         "Retry-After limited by MaxDelay",
+			policy:       exposed.RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond},
+			statuses:     []int{http.StatusTooManyRequests},
+			retryAfter:   "3600",
+			wantRequests: 2,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if requests <= len(tc.statuses) {
+					if tc.retryAfter != "" {
+						w.Header().Set("Retry-After", tc.retryAfter)
+					}
+					w.WriteHeader(tc.statuses[requests-1])
+					return
+				}
+				_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+			}))
+			defer server.Close()
+
+			c := exposed.NewPwnedClient(&http.Client{}, server.URL, exposed.WithRetryPolicy(tc.policy))
+			_, err := c.CheckPwnedPassword("password", "sha1")
+
+			if (err != nil) != tc.wantErr {
+				t.Errorf("CheckPwnedPassword() error = %v, expectedErr %v", err, tc.wantErr)
+			}
+			if requests != tc.wantRequests {
+				t.Errorf("requests = %d, expected %d", requests, tc.wantRequests)
+			}
+		})
+	}
+}
+
+// fakeClock is a Clock that records requested delays and advances
+// immediately instead of waiting.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	delays []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.delays = append(c.delays, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+func TestRetryWithClock(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1, 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		case 3:
+			// an HTTP date 90s after the fake clock's current time
+			w.Header().Set("Retry-After", clock.Now().Add(90*time.Second).Format(http.TimeFormat))
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+		}
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL,
+		exposed.WithClock(clock),
+		exposed.WithRetryPolicy(exposed.RetryPolicy{
+			MaxRetries: 3,
+			BaseDelay:  time.Minute,
+		}))
+
+	count, err := c.CheckPwnedPassword("password", "sha1")
+	if err != nil {
+		t.Fatalf("CheckPwnedPassword() error = %v", err)
+	}
+	if count != 10434004 {
+		t.Errorf("CheckPwnedPassword() = %v, expected %v", count, 10434004)
+	}
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 90 * time.Second}
+	if len(clock.delays) != len(want) {
+		t.Fatalf("delays = %v, expected %v", clock.delays, want)
+	}
+	for i := range want {
+		if clock.delays[i] != want[i] {
+			t.Errorf("delays[%d] = %v, expected %v", i, clock.delays[i], want[i])
+		}
+	}
+	stats := c.Stats()
+	if stats.Requests != 4 || stats.Retries != 3 {
+		t.Errorf("Stats() = %+v, expected 4 requests and 3 retries", stats)
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import "context"
+
+// SeverityLevel labels counts of at least MinCount.
+type SeverityLevel struct {
+	MinCount int
+	Label    string
+}
+
+// SeverityScale classifies breach counts into named severity levels, e.g.,
+// to tell a user that a password is not just exposed but very common. The
+// level with the highest MinCount that does not exceed the count is used,
+// so the order of the levels does not matter.
+type SeverityScale []SeverityLevel
+
+// DefaultSeverityScale labels counts consistently with the default corpus
+// size of EstimateRarity.
+var DefaultSeverityScale = SeverityScale{
+	{MinCount: 0, Label: "not found"},
+	{MinCount: 1, Label: "rare"},
+	{MinCount: 10, Label: "common"},
+	{MinCount: 100, Label: "very common"},
+	{MinCount: 10000, Label: "extremely common"},
+}
+
+// Label returns the label for count, or an empty string if no level
+// applies.
+func (s SeverityScale) Label(count int) string {
+	var level *SeverityLevel
+	for i := range s {
+		l := &s[i]
+		if l.MinCount <= count && (level == nil || l.MinCount > level.MinCount) {
+			level = l
+		}
+	}
+	if level == nil {
+		return ""
+	}
+	return level.Label
+}
+
+// CheckSeverity checks if the password has been exposed in breaches and
+// returns the label for its count from scale along with the count. If scale
+// is nil, DefaultSeverityScale is used.
+func (c *PwnedClient) CheckSeverity(ctx context.Context, password, mode string, scale SeverityScale) (label string, count int, err error) {
+	count, err = c.CheckPwnedPasswordContext(ctx, password, mode)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if scale == nil {
+		scale = DefaultSeverityScale
+	}
+	return scale.Label(count), count, nil
+}
+
+// CheckSeverity checks the password using the default client and returns
+// the label for its count from scale along with the count. See
+// PwnedClient.CheckSeverity.
+func CheckSeverity(ctx context.Context, password, mode string, scale SeverityScale) (label string, count int, err error) {
+	return defaultClient().CheckSeverity(ctx, password, mode, scale)
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestSeverityScaleLabel(t *testing.T) {
+	custom := exposed.SeverityScale{
+		{MinCount: 1000, Label: "block"},
+		{MinCount: 1, Label: "warn"},
+	}
+
+	tests := []struct {
+		scale exposed.SeverityScale
+		count int
+		want  string
+	}{
+		{exposed.DefaultSeverityScale, 0, "not found"},
+		{exposed.DefaultSeverityScale, 1, "rare"},
+		{exposed.DefaultSeverityScale, 9, "rare"},
+		{exposed.DefaultSeverityScale, 10, "common"},
+		{exposed.DefaultSeverityScale, 100, "very common"},
+		{exposed.DefaultSeverityScale, 10434004, "extremely common"},
+		{custom, 0, ""},
+		{custom, 999, "warn"},
+		{custom, 1000, "block"},
+		{nil, 5, ""},
+	}
+
+	for _, tc := range tests {
+		if got := tc.scale.Label(tc.count); got != tc.want {
+			t.Errorf("Label(%d) = %q, expected %q", tc.count, got, tc.want)
+		}
+	}
+}
+
+func TestCheckSeverity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+
+	label, count, err := c.CheckSeverity(context.Background(), "password", "sha1", nil)
+	if err != nil {
+		t.Fatalf("CheckSeverity() error = %v", err)
+	}
+	if label != "extremely common" || count != 10434004 {
+		t.Errorf("CheckSeverity() = %q, %d, expected %q, %d", label, count, "extremely common", 10434004)
+	}
+}
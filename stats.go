@@ -0,0 +1,40 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Stats reports the upstream activity of a client.
+type Stats struct {
+	Requests int64 // upstream requests sent, including retries
+	Retries  int64 // requests that were retries of a failed attempt
+
+	// LimiterWait is the total time requests waited on the rate limiter
+	// set by WithRateLimit, summed across goroutines.
+	LimiterWait time.Duration
+}
+
+// clientStats holds the counters behind Stats.
+type clientStats struct {
+	requests atomic.Int64
+	retries  atomic.Int64
+	wait     atomic.Int64 // nanoseconds
+	latency  latencyHistogram
+}
+
+// Stats returns the upstream activity of the client so far. It is safe to
+// call concurrently with lookups.
+func (c *PwnedClient) Stats() Stats {
+	if c.stats == nil {
+		return Stats{}
+	}
+	return Stats{
+		Requests: c.stats.requests.Load(),
+		Retries:  c.stats.retries.Load(),
+
+		LimiterWait: time.Duration(c.stats.wait.Load()),
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+)
+
+// transportConfig holds transport settings applied by NewPwnedClient.
+type transportConfig struct {
+	maxIdleConnsPerHost int
+	idleConnTimeout     time.Duration
+	dialContext         func(ctx context.Context, network, addr string) (net.Conn, error)
+	pinnedCert          []byte // SHA-256 fingerprint of the leaf certificate
+}
+
+// isZero reports whether no transport settings were configured.
+func (tc transportConfig) isZero() bool {
+	return tc.maxIdleConnsPerHost == 0 && tc.idleConnTimeout == 0 &&
+		tc.dialContext == nil && tc.pinnedCert == nil
+}
+
+// NewTransport returns a new transport with the settings used by
+// DefaultPwnedClient, e.g., to share with WithSharedTransport.
+func NewTransport() *http.Transport {
+	return newDefaultTransport()
+}
+
+// WithSharedTransport sends requests through t, which can be shared by any
+// number of clients, e.g., one per tenant, so they share one connection pool
+// instead of each building its own. A transport is safe for concurrent use
+// and is never modified by a client. Settings applied per request, such as
+// the base URL, headers, API key, timeout, retries, rate limit, and cache,
+// remain separate for each client. The transport options, such as
+// WithMaxIdleConnsPerHost and WithUnixSocket, are ignored since they would
+// modify t; configure t directly instead.
+func WithSharedTransport(t *http.Transport) Option {
+	return func(c *PwnedClient) {
+		c.sharedTransport = t
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the maximum number of idle connections kept
+// per host, which helps high-concurrency scans reuse connections. It only
+// applies if the client's Transport is an *http.Transport.
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(c *PwnedClient) {
+		c.transport.maxIdleConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout sets how long an idle connection remains in the pool
+// before being closed. It only applies if the client's Transport is an
+// *http.Transport.
+func WithIdleConnTimeout(d time.Duration) Option {
+	return func(c *PwnedClient) {
+		c.transport.idleConnTimeout = d
+	}
+}
+
+// applyTransportConfig applies the configured transport settings to a clone
+// of the HTTP client's transport so that a caller's client and transport are
+// never modified. A nil Transport is treated as http.DefaultTransport. A
+// shared transport is used as is.
+func (c *PwnedClient) applyTransportConfig() {
+	if c.sharedTransport != nil {
+		if c.transport.pinnedCert != nil {
+			c.setConfigErr(errors.New("WithPinnedCert cannot be used with WithSharedTransport, pin the shared transport instead"))
+		}
+		client := *c.httpClient
+		client.Transport = c.sharedTransport
+		c.httpClient = &client
+		return
+	}
+	if c.transport.isZero() {
+		return
+	}
+
+	rt := c.httpClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	base, ok := rt.(*http.Transport)
+	if !ok {
+		if c.transport.pinnedCert != nil {
+			c.setConfigErr(fmt.Errorf("WithPinnedCert requires an *http.Transport, got %T", rt))
+		}
+		return
+	}
+
+	t := base.Clone()
+	if c.transport.maxIdleConnsPerHost > 0 {
+		t.MaxIdleConnsPerHost = c.transport.maxIdleConnsPerHost
+		if t.MaxIdleConns > 0 && t.MaxIdleConns < t.MaxIdleConnsPerHost {
+			t.MaxIdleConns = t.MaxIdleConnsPerHost
+		}
+	}
+	if c.transport.idleConnTimeout > 0 {
+		t.IdleConnTimeout = c.transport.idleConnTimeout
+	}
+	if c.transport.dialContext != nil {
+		t.DialContext = c.transport.dialContext
+		t.DialTLSContext = nil
+		t.Proxy = nil
+	}
+	if c.transport.pinnedCert != nil {
+		t.TLSClientConfig = pinnedTLSConfig(t.TLSClientConfig, c.transport.pinnedCert)
+	}
+
+	client := *c.httpClient
+	client.Transport = t
+	c.httpClient = &client
+}
+
+// WithDialContext sets the function used to open connections, e.g., to reach
+// a local mirror through a sidecar. Proxies from the environment are not
+// used with a custom dialer. It only applies if the client's Transport is an
+// *http.Transport.
+func WithDialContext(dial func(ctx context.Context, network, addr string) (net.Conn, error)) Option {
+	return func(c *PwnedClient) {
+		c.transport.dialContext = dial
+	}
+}
+
+// WithUnixSocket connects to the range API through the Unix domain socket at
+// path instead of TCP, e.g., to reach a mirror running as a sidecar. The
+// host in the base URL is then only used for the Host header, so use a base
+// URL like "http://localhost/range" with the path the mirror serves, e.g.,
+//
+//	exposed.NewPwnedClient(nil, "http://localhost/range",
+//		exposed.WithUnixSocket("/var/run/hibp/mirror.sock"))
+//
+// It only applies if the client's Transport is an *http.Transport.
+func WithUnixSocket(path string) Option {
+	return WithDialContext(func(ctx context.Context, _, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", path)
+	})
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTransportConfig(t *testing.T) {
+	base := newDefaultTransport()
+	client := &http.Client{Transport: base}
+
+	c := NewPwnedClient(client, BaseURL,
+		WithMaxIdleConnsPerHost(200),
+		WithIdleConnTimeout(5*time.Second),
+	)
+
+	got, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Transport = %T, expected *http.Transport", c.httpClient.Transport)
+	}
+	if got.MaxIdleConnsPerHost != 200 {
+		t.Errorf("MaxIdleConnsPerHost = %d, expected %d", got.MaxIdleConnsPerHost, 200)
+	}
+	if got.MaxIdleConns != 200 {
+		t.Errorf("MaxIdleConns = %d, expected %d", got.MaxIdleConns, 200)
+	}
+	if got.IdleConnTimeout != 5*time.Second {
+		t.Errorf("IdleConnTimeout = %v, expected %v", got.IdleConnTimeout, 5*time.Second)
+	}
+
+	// the caller's client and transport must not be modified
+	if client.Transport != base {
+		t.Error("caller's client Transport was replaced")
+	}
+	if base.MaxIdleConnsPerHost != 0 || base.IdleConnTimeout != 90*time.Second {
+		t.Error("caller's transport was modified")
+	}
+}
+
+func TestTransportConfigDefaults(t *testing.T) {
+	c := NewPwnedClient(nil, BaseURL)
+
+	got, ok := c.httpClient.Transport.(*http.Transport)
+	if !ok {
+		t.Fatalf("Transport = %T, expected *http.Transport", c.httpClient.Transport)
+	}
+	if got.MaxIdleConns != 100 || got.IdleConnTimeout != 90*time.Second {
+		t.Errorf("default transport settings changed: MaxIdleConns = %d, IdleConnTimeout = %v",
+			got.MaxIdleConns, got.IdleConnTimeout)
+	}
+	if c.httpClient.Timeout != 30*time.Second {
+		t.Errorf("Timeout = %v, expected %v", c.httpClient.Timeout, 30*time.Second)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	client := &http.Client{Timeout: time.Minute}
+	c := NewPwnedClient(client, BaseURL, WithTimeout(time.Second))
+
+	if c.httpClient.Timeout != time.Second {
+		t.Errorf("Timeout = %v, expected %v", c.httpClient.Timeout, time.Second)
+	}
+	if client.Timeout != time.Minute {
+		t.Errorf("caller's client Timeout = %v, expected %v", client.Timeout, time.Minute)
+	}
+}
+
+func TestWithSharedTransport(t *testing.T) {
+	var agents []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		agents = append(agents, r.Header.Get("User-Agent"))
+	}))
+	defer server.Close()
+
+	shared := NewTransport()
+	a := NewPwnedClient(nil, server.URL, WithSharedTransport(shared), WithUserAgent("tenant-a"),
+		WithTimeout(time.Second), WithMaxIdleConnsPerHost(50))
+	b := NewPwnedClient(nil, server.URL, WithSharedTransport(shared), WithUserAgent("tenant-b"))
+
+	if a.httpClient.Transport != shared || b.httpClient.Transport != shared {
+		t.Fatal("clients do not use the shared transport")
+	}
+	if shared.MaxIdleConnsPerHost != 0 {
+		t.Error("shared transport was modified")
+	}
+	if a.httpClient.Timeout != time.Second || b.httpClient.Timeout != 30*time.Second {
+		t.Errorf("timeouts = %v and %v, expected per-client timeouts", a.httpClient.Timeout, b.httpClient.Timeout)
+	}
+
+	for _, c := range []*PwnedClient{a, b} {
+		if _, err := c.CheckPwnedPassword("password", "sha1"); err != nil {
+			t.Fatalf("CheckPwnedPassword() error = %v", err)
+		}
+	}
+	if len(agents) != 2 || agents[0] != "tenant-a" || agents[1] != "tenant-b" {
+		t.Errorf("User-Agent headers = %v, expected per-client headers", agents)
+	}
+}
+
+func TestWithPinnedCert(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("1E4C9B93F3F0682250B6CF8331B7EE68FD8:10434004\r\n"))
+	}))
+	defer server.Close()
+
+	sum := sha256.Sum256(server.Certificate().Raw)
+	pin := hex.EncodeToString(sum[:])
+
+	c := NewPwnedClient(server.Client(), server.URL, WithPinnedCert(pin))
+	if count, err := c.CheckPwnedPassword("password", "sha1"); err != nil || count != 10434004 {
+		t.Errorf("CheckPwnedPassword() = %d, %v, expected 10434004, nil", count, err)
+	}
+
+	other := strings.Repeat("AB:", sha256.Size-1) + "AB"
+	c = NewPwnedClient(server.Client(), server.URL, WithPinnedCert(other))
+	if _, err := c.CheckPwnedPassword("password", "sha1"); !errors.Is(err, ErrCertificatePin) {
+		t.Errorf("CheckPwnedPassword() error = %v, expected %v", err, ErrCertificatePin)
+	}
+
+	for _, bad := range []string{"", "xyz", pin[:10]} {
+		c = NewPwnedClient(server.Client(), server.URL, WithPinnedCert(bad))
+		if _, err := c.CheckPwnedPassword("password", "sha1"); err == nil {
+			t.Errorf("WithPinnedCert(%q) expected error", bad)
+		}
+	}
+
+	c = NewPwnedClient(server.Client(), server.URL, WithPinnedCert(pin), WithSharedTransport(NewTransport()))
+	if _, err := c.CheckPwnedPassword("password", "sha1"); err == nil {
+		t.Error("WithPinnedCert with WithSharedTransport expected error")
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"context"
+	"io"
+	"net/http"
+)
+
+// Warmup opens a connection to the API host and leaves it idle in the
+// client's connection pool, so the first lookup does not pay for the DNS
+// lookup and TCP and TLS handshakes, e.g., at startup of a service with
+// latency-sensitive signup flows. It sends a HEAD request for a fixed range
+// through the client's transport with its headers, so it reveals nothing
+// about the passwords checked later. It is not retried and is not limited
+// by WithRateLimit or WithRequestBudget.
+//
+// Any HTTP response means the connection is warm, so only a failure to
+// connect is returned as an error. Warmup is optional and safe to skip; a
+// warm connection is only reused if it is still idle when the first lookup
+// is made, see WithIdleConnTimeout.
+func (c *PwnedClient) Warmup(ctx context.Context) error {
+	u, err := buildURL(c.baseURL, c.zeroPrefix(), "sha1")
+	if err != nil {
+		return err
+	}
+
+	req, err := c.newRangeRequest(ctx, u)
+	if err != nil {
+		return err
+	}
+	req.Method = http.MethodHead
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	// drain body to return the connection to the pool
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestWarmup(t *testing.T) {
+	var conns, heads atomic.Int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			heads.Add(1)
+			return
+		}
+		_, _ = w.Write([]byte(readFile("testdata/5BAA6")))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.Start()
+	defer server.Close()
+
+	c := exposed.NewPwnedClient(&http.Client{}, server.URL)
+	if err := c.Warmup(context.Background()); err != nil {
+		t.Fatalf("Warmup() error = %v", err)
+	}
+	if got := heads.Load(); got != 1 {
+		t.Errorf("got %d HEAD requests, expected 1", got)
+	}
+
+	count, err := c.CheckPwnedPassword("password", "sha1")
+	if err != nil || count != 10434004 {
+		t.Fatalf("CheckPwnedPassword() = %d, %v, expected 10434004, nil", count, err)
+	}
+	if got := conns.Load(); got != 1 {
+		t.Errorf("got %d connections, expected the warm one to be reused", got)
+	}
+
+	// a server that cannot be reached is an error
+	server.Close()
+	if err := c.Warmup(context.Background()); err == nil {
+		t.Error("Warmup() expected error for closed server")
+	}
+}
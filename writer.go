@@ -0,0 +1,322 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// Record is a single result written by a ResultWriter.
+type Record struct {
+	Input string // password, hash, or identifier that was checked
+	Count int    // number of times exposed, 0 if not found or blocked
+
+	// Blocked reports whether the input matched a blocklist, see
+	// Result.Blocked, in which case Count is 0 since it was not looked up.
+	Blocked bool
+
+	// Prefix is the hash prefix sent to the API, written only if not empty
+	// to show that the password and full hash never left the machine.
+	Prefix string
+
+	// Hash is the hash of the input, or a masked form of it, written only
+	// if not empty, e.g., to join results with other hash-based data.
+	Hash string
+
+	// Source is where the input was read from, e.g., a file name, written
+	// only if not empty to tell apart the results of several inputs.
+	Source string
+}
+
+// Found reports whether the input was found in breaches.
+func (r Record) Found() bool {
+	return r.Count > 0
+}
+
+// ResultWriter writes results to an underlying io.Writer in a particular
+// format.
+type ResultWriter interface {
+	// WriteRecord writes a single result.
+	WriteRecord(r Record) error
+
+	// Flush writes any buffered data to the underlying io.Writer.
+	Flush() error
+}
+
+// ResultFormats are the formats supported by NewResultWriter.
+var ResultFormats = []string{"text", "json", "csv"}
+
+// NewResultWriter returns a ResultWriter for the named format writing to w.
+// The format must be one of ResultFormats.
+func NewResultWriter(format string, w io.Writer) (ResultWriter, error) {
+	switch format {
+	case "text":
+		return NewTextResultWriter(w), nil
+	case "json":
+		return NewJSONResultWriter(w), nil
+	case "csv":
+		return NewCSVResultWriter(w), nil
+	default:
+		return nil, fmt.Errorf("invalid format: %q", format)
+	}
+}
+
+// formatIntWithSeparator formats an integer with a specified single-character
+// separator, grouping the digits in threes. It supports both negative and
+// non-negative integers, and separators of any encoded length, e.g., the
+// narrow no-break space U+202F used in some locales.
+func formatIntWithSeparator(n int, separator rune) string {
+	isNegative := n < 0
+	if isNegative {
+		n = -n
+	}
+
+	s := strconv.Itoa(n)
+	l := len(s)
+	if l <= 3 {
+		if isNegative {
+			return "-" + s
+		}
+		return s
+	}
+
+	// a strings.Builder, unlike a bytes.Buffer, allocates only the size
+	// requested and returns it without a copy
+	var buf strings.Builder
+	buf.Grow(formattedIntLen(l, isNegative, separator))
+
+	if isNegative {
+		buf.WriteByte('-')
+	}
+
+	// Process initial segment
+	mod := l % 3
+	if mod > 0 {
+		buf.WriteString(s[:mod])
+		if l > mod {
+			buf.WriteRune(separator)
+		}
+	}
+
+	// Process remaining segments
+	for p := mod; p < l; p += 3 {
+		buf.WriteString(s[p : p+3])
+		if p+3 < l {
+			buf.WriteRune(separator)
+		}
+	}
+
+	return buf.String()
+}
+
+// formattedIntLen returns the length in bytes of an integer of digits
+// decimal digits formatted by formatIntWithSeparator, so the result can be
+// built without reallocating.
+func formattedIntLen(digits int, isNegative bool, separator rune) int {
+	// an invalid separator is written as utf8.RuneError
+	separatorLen := utf8.RuneLen(separator)
+	if separatorLen < 0 {
+		separatorLen = utf8.RuneLen(utf8.RuneError)
+	}
+
+	n := digits + (digits-1)/3*separatorLen
+	if isNegative {
+		n++
+	}
+	return n
+}
+
+// TextResultWriter writes results as human-readable lines, e.g.,
+// "password: exposed 10,434,004 times".
+type TextResultWriter struct {
+	// Separator groups the digits of counts in threes, ',' if zero. It may
+	// be any rune, e.g., '.' or the narrow no-break space U+202F, to match
+	// the conventions of a locale.
+	Separator rune
+
+	w io.Writer
+}
+
+// NewTextResultWriter returns a TextResultWriter writing to w.
+func NewTextResultWriter(w io.Writer) *TextResultWriter {
+	return &TextResultWriter{w: w}
+}
+
+// WriteRecord writes r as a line of text.
+func (tw *TextResultWriter) WriteRecord(r Record) error {
+	var sent string
+	if r.Prefix != "" {
+		sent = " (sent prefix " + r.Prefix + ")"
+	}
+	if r.Hash != "" {
+		sent += " (hash " + r.Hash + ")"
+	}
+
+	input := r.Input
+	if r.Source != "" {
+		input = r.Source + ": " + input
+	}
+
+	if r.Blocked {
+		_, err := fmt.Fprintf(tw.w, "%s: blocked%s\n", input, sent)
+		return err
+	}
+	if !r.Found() {
+		_, err := fmt.Fprintf(tw.w, "%s: not found%s\n", input, sent)
+		return err
+	}
+
+	separator := tw.Separator
+	if separator == 0 {
+		separator = ','
+	}
+	_, err := fmt.Fprintf(tw.w, "%s: exposed %s times%s\n",
+		input, formatIntWithSeparator(r.Count, separator), sent)
+	return err
+}
+
+// Flush does nothing since TextResultWriter does not buffer.
+func (tw *TextResultWriter) Flush() error {
+	return nil
+}
+
+// JSONResultWriter writes results as JSON objects, one per line.
+type JSONResultWriter struct {
+	enc *json.Encoder
+}
+
+// jsonRecord is the JSON representation of a Record.
+type jsonRecord struct {
+	Input   string `json:"input"`
+	Count   int    `json:"count"`
+	Found   bool   `json:"found"`
+	Blocked bool   `json:"blocked,omitempty"`
+
+	Prefix string `json:"prefix,omitempty"`
+	Hash   string `json:"hash,omitempty"`
+	Source string `json:"source,omitempty"`
+}
+
+// NewJSONResultWriter returns a JSONResultWriter writing to w.
+func NewJSONResultWriter(w io.Writer) *JSONResultWriter {
+	return &JSONResultWriter{enc: json.NewEncoder(w)}
+}
+
+// WriteRecord writes r as a JSON object followed by a newline.
+func (jw *JSONResultWriter) WriteRecord(r Record) error {
+	return jw.enc.Encode(jsonRecord{
+		Input:   r.Input,
+		Count:   r.Count,
+		Found:   r.Found(),
+		Blocked: r.Blocked,
+		Prefix:  r.Prefix,
+		Hash:    r.Hash,
+		Source:  r.Source,
+	})
+}
+
+// Flush does nothing since JSONResultWriter does not buffer.
+func (jw *JSONResultWriter) Flush() error {
+	return nil
+}
+
+// CSVResultWriter writes results as CSV with a header row. If the first
+// record has a Prefix, Hash, or Source, a prefix, hash, or source column is
+// included.
+type CSVResultWriter struct {
+	w           *csv.Writer
+	wroteHeader bool
+	withPrefix  bool
+	withHash    bool
+	withSource  bool
+}
+
+// csvHeader is the first row written by CSVResultWriter.
+var csvHeader = []string{"input", "count", "found", "blocked"}
+
+// NewCSVResultWriter returns a CSVResultWriter writing to w.
+func NewCSVResultWriter(w io.Writer) *CSVResultWriter {
+	return &CSVResultWriter{w: csv.NewWriter(w)}
+}
+
+// WriteRecord writes r as a CSV row, preceded by the header row if this is
+// the first record.
+func (cw *CSVResultWriter) WriteRecord(r Record) error {
+	if !cw.wroteHeader {
+		cw.withPrefix = r.Prefix != ""
+		cw.withHash = r.Hash != ""
+		cw.withSource = r.Source != ""
+		header := csvHeader[:len(csvHeader):len(csvHeader)]
+		if cw.withPrefix {
+			header = append(header, "prefix")
+		}
+		if cw.withHash {
+			header = append(header, "hash")
+		}
+		if cw.withSource {
+			header = append(header, "source")
+		}
+		if err := cw.w.Write(header); err != nil {
+			return err
+		}
+		cw.wroteHeader = true
+	}
+
+	row := []string{
+		r.Input,
+		strconv.Itoa(r.Count),
+		strconv.FormatBool(r.Found()),
+		strconv.FormatBool(r.Blocked),
+	}
+	if cw.withPrefix {
+		row = append(row, r.Prefix)
+	}
+	if cw.withHash {
+		row = append(row, r.Hash)
+	}
+	if cw.withSource {
+		row = append(row, r.Source)
+	}
+	return cw.w.Write(row)
+}
+
+// Flush writes any buffered rows to the underlying io.Writer.
+func (cw *CSVResultWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+// TemplateResultWriter writes each result by executing a text/template with
+// the Record as data, followed by a newline, e.g., with
+// "{{.Input}}\t{{.Count}}\t{{.Found}}".
+type TemplateResultWriter struct {
+	w    io.Writer
+	tmpl *template.Template
+}
+
+// NewTemplateResultWriter returns a TemplateResultWriter writing to w using
+// tmpl.
+func NewTemplateResultWriter(w io.Writer, tmpl *template.Template) *TemplateResultWriter {
+	return &TemplateResultWriter{w: w, tmpl: tmpl}
+}
+
+// WriteRecord writes r using the template followed by a newline.
+func (tw *TemplateResultWriter) WriteRecord(r Record) error {
+	if err := tw.tmpl.Execute(tw.w, r); err != nil {
+		return err
+	}
+	_, err := io.WriteString(tw.w, "\n")
+	return err
+}
+
+// Flush does nothing since TemplateResultWriter does not buffer.
+func (tw *TemplateResultWriter) Flush() error {
+	return nil
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestFormatIntWithSeparator(t *testing.T) {
+	tests := []struct {
+		n         int
+		separator rune
+		want      string
+	}{
+		{0, ',', "0"},
+		{999, ',', "999"},
+		{-999, ',', "-999"},
+		{1000, ',', "1,000"},
+		{-1234567, ',', "-1,234,567"},
+		{10434004, ' ', "10 434 004"},
+		{-10434004, '😀', "-10😀434😀004"},
+		{1000, utf8.MaxRune + 1, "1�000"},
+	}
+	for _, tc := range tests {
+		if got := formatIntWithSeparator(tc.n, tc.separator); got != tc.want {
+			t.Errorf("formatIntWithSeparator(%d, %q) = %q, expected %q", tc.n, tc.separator, got, tc.want)
+		}
+	}
+}
+
+func TestFormattedIntLen(t *testing.T) {
+	// the estimate must be exact, or the result is reallocated as it grows
+	for _, n := range []int{1000, -1000, 1234567890, -1234567890, math.MaxInt, math.MinInt + 1} {
+		for _, separator := range []rune{',', '\u00a0', '\u202f', '😀', utf8.MaxRune + 1} {
+			s := strconv.Itoa(n)
+			digits := len(strings.TrimPrefix(s, "-"))
+			got := formattedIntLen(digits, n < 0, separator)
+			if want := len(formatIntWithSeparator(n, separator)); got != want {
+				t.Errorf("formattedIntLen(%d, %v, %q) = %d, expected %d", digits, n < 0, separator, got, want)
+			}
+		}
+	}
+}
+
+func BenchmarkFormatIntWithSeparator(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		formatIntWithSeparator(-1234567890, ' ')
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2024 Bill Nixon
+
+package exposed_test
+
+import (
+	"bytes"
+	"testing"
+	"text/template"
+
+	"github.com/bnixon67/exposed"
+)
+
+func TestResultWriter(t *testing.T) {
+	records := []exposed.Record{
+		{Input: "password", Count: 10434004},
+		{Input: `say "hi", bye`, Count: 0},
+		{Input: "acme", Blocked: true},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "text",
+			want:   "password: exposed 10,434,004 times\nsay \"hi\", bye: not found\nacme: blocked\n",
+		},
+		{
+			format: "json",
+			want: `{"input":"password","count":10434004,"found":true}` + "\n" +
+				`{"input":"say \"hi\", bye","count":0,"found":false}` + "\n" +
+				`{"input":"acme","count":0,"found":false,"blocked":true}` + "\n",
+		},
+		{
+			format: "csv",
+			want:   "input,count,found,blocked\npassword,10434004,true,false\n\"say \"\"hi\"\", bye\",0,false,false\nacme,0,false,true\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := exposed.NewResultWriter(tc.format, &buf)
+			if err != nil {
+				t.Fatalf("NewResultWriter() error = %v", err)
+			}
+
+			for _, r := range records {
+				if err := w.WriteRecord(r); err != nil {
+					t.Fatalf("WriteRecord() error = %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("output = %q, expected %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestNewResultWriterInvalidFormat(t *testing.T) {
+	if _, err := exposed.NewResultWriter("xml", &bytes.Buffer{}); err == nil {
+		t.Error("NewResultWriter() error = nil, expected error")
+	}
+}
+
+func TestResultWriterPrefix(t *testing.T) {
+	records := []exposed.Record{
+		{Input: "password", Count: 10434004, Prefix: "5BAA6"},
+		{Input: "other", Count: 0, Prefix: "8846F"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "text",
+			want:   "password: exposed 10,434,004 times (sent prefix 5BAA6)\nother: not found (sent prefix 8846F)\n",
+		},
+		{
+			format: "json",
+			want: `{"input":"password","count":10434004,"found":true,"prefix":"5BAA6"}` + "\n" +
+				`{"input":"other","count":0,"found":false,"prefix":"8846F"}` + "\n",
+		},
+		{
+			format: "csv",
+			want:   "input,count,found,blocked,prefix\npassword,10434004,true,false,5BAA6\nother,0,false,false,8846F\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := exposed.NewResultWriter(tc.format, &buf)
+			if err != nil {
+				t.Fatalf("NewResultWriter() error = %v", err)
+			}
+
+			for _, r := range records {
+				if err := w.WriteRecord(r); err != nil {
+					t.Fatalf("WriteRecord() error = %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("output = %q, expected %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResultWriterHash(t *testing.T) {
+	records := []exposed.Record{
+		{Input: "password", Count: 10434004, Hash: "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"},
+		{Input: "other", Count: 0, Hash: "8846F..."},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "text",
+			want:   "password: exposed 10,434,004 times (hash 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8)\nother: not found (hash 8846F...)\n",
+		},
+		{
+			format: "json",
+			want: `{"input":"password","count":10434004,"found":true,"hash":"5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"}` + "\n" +
+				`{"input":"other","count":0,"found":false,"hash":"8846F..."}` + "\n",
+		},
+		{
+			format: "csv",
+			want:   "input,count,found,blocked,hash\npassword,10434004,true,false,5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8\nother,0,false,false,8846F...\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := exposed.NewResultWriter(tc.format, &buf)
+			if err != nil {
+				t.Fatalf("NewResultWriter() error = %v", err)
+			}
+
+			for _, r := range records {
+				if err := w.WriteRecord(r); err != nil {
+					t.Fatalf("WriteRecord() error = %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("output = %q, expected %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestResultWriterSource(t *testing.T) {
+	records := []exposed.Record{
+		{Input: "password", Count: 10434004, Source: "a.txt"},
+		{Input: "acme", Blocked: true, Source: "b.txt"},
+		{Input: "other", Count: 0, Source: "b.txt"},
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: "text",
+			want:   "a.txt: password: exposed 10,434,004 times\nb.txt: acme: blocked\nb.txt: other: not found\n",
+		},
+		{
+			format: "json",
+			want: `{"input":"password","count":10434004,"found":true,"source":"a.txt"}` + "\n" +
+				`{"input":"acme","count":0,"found":false,"blocked":true,"source":"b.txt"}` + "\n" +
+				`{"input":"other","count":0,"found":false,"source":"b.txt"}` + "\n",
+		},
+		{
+			format: "csv",
+			want:   "input,count,found,blocked,source\npassword,10434004,true,false,a.txt\nacme,0,false,true,b.txt\nother,0,false,false,b.txt\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			w, err := exposed.NewResultWriter(tc.format, &buf)
+			if err != nil {
+				t.Fatalf("NewResultWriter() error = %v", err)
+			}
+
+			for _, r := range records {
+				if err := w.WriteRecord(r); err != nil {
+					t.Fatalf("WriteRecord() error = %v", err)
+				}
+			}
+			if err := w.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+
+			if got := buf.String(); got != tc.want {
+				t.Errorf("output = %q, expected %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestTemplateResultWriter(t *testing.T) {
+	tmpl := template.Must(template.New("output").Parse("{{.Input}}\t{{.Count}}\t{{.Found}}"))
+
+	var buf bytes.Buffer
+	w := exposed.NewTemplateResultWriter(&buf, tmpl)
+	for _, r := range []exposed.Record{{Input: "password", Count: 10434004}, {Input: "other"}} {
+		if err := w.WriteRecord(r); err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := "password\t10434004\ttrue\nother\t0\tfalse\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, expected %q", got, want)
+	}
+}
+
+func TestTextResultWriterSeparator(t *testing.T) {
+	tests := []struct {
+		separator rune
+		count     int
+		want      string
+	}{
+		{0, 10434004, "password: exposed 10,434,004 times\n"},
+		{'.', 10434004, "password: exposed 10.434.004 times\n"},
+		{'\u202f', 10434004, "password: exposed 10\u202f434\u202f004 times\n"},
+		{'\u202f', 1234, "password: exposed 1\u202f234 times\n"},
+		{'\u202f', 123, "password: exposed 123 times\n"},
+		{'😀', 1000000, "password: exposed 1😀000😀000 times\n"},
+	}
+
+	for _, tc := range tests {
+		var buf bytes.Buffer
+		w := exposed.NewTextResultWriter(&buf)
+		w.Separator = tc.separator
+		if err := w.WriteRecord(exposed.Record{Input: "password", Count: tc.count}); err != nil {
+			t.Fatalf("WriteRecord() error = %v", err)
+		}
+		if got := buf.String(); got != tc.want {
+			t.Errorf("separator %q: output = %q, expected %q", tc.separator, got, tc.want)
+		}
+	}
+}